@@ -0,0 +1,38 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// statusLabel returns the label -accessible prefixes a trimmed
+// result line with, such as "[FAIL]", or "" for other lines.
+func statusLabel(trimmed string) string {
+	status := resultStatus(trimmed)
+	if s, _, ok := packageResult(trimmed); ok {
+		status = packageStatuses[s]
+	}
+	if trimmed == "PASS" || trimmed == "FAIL" {
+		status = trimmed
+	}
+	if status == "FAIL" && isFlaky(testName(trimmed)) {
+		status = "FLAKY"
+	}
+	if status == "" {
+		return ""
+	}
+	return "[" + status + "]"
+}
+
+// labelLine prefixes a result line with its status label,
+// after its indentation, so the status isn't conveyed by
+// color alone.
+func labelLine(line, trimmed string) string {
+	label := statusLabel(trimmed)
+	if label == "" {
+		return line
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return indent + label + " " + line[len(indent):]
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// goValueFlags are the go test and build flags that take their
+// value as a separate argument, as in "-run TestA".
+var goValueFlags = map[string]bool{
+	"asmflags": true, "bench": true, "benchtime": true, "blockprofile": true,
+	"blockprofilerate": true, "C": true, "compiler": true, "count": true,
+	"coverpkg": true, "covermode": true, "coverprofile": true, "cpu": true,
+	"cpuprofile": true, "exec": true, "fuzz": true, "fuzzminimizetime": true,
+	"fuzztime": true, "gccgoflags": true, "gcflags": true, "installsuffix": true,
+	"ldflags": true, "list": true, "memprofile": true, "memprofilerate": true,
+	"mod": true, "modfile": true, "mutexprofile": true, "mutexprofilefraction": true,
+	"o": true, "outputdir": true, "overlay": true, "parallel": true,
+	"pgo": true, "pkgdir": true, "run": true, "shuffle": true, "skip": true,
+	"tags": true, "timeout": true, "toolexec": true, "trace": true, "vet": true,
+}
+
+// splitArgs splits go test args into flags, package patterns,
+// and the -args for the test binary.
+func splitArgs(args []string) (flagArgs, pkgs, testArgs []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			return flagArgs, pkgs, args[i:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			pkgs = append(pkgs, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		name = strings.TrimPrefix(name, "test.")
+		if !strings.Contains(name, "=") && goValueFlags[name] && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	return flagArgs, pkgs, nil
+}
+
+// flagValue returns the value of the go test flag name in flagArgs,
+// as split by splitArgs, and whether it is set.
+func flagValue(flagArgs []string, name string) (string, bool) {
+	value, found := "", false
+	for i := 0; i < len(flagArgs); i++ {
+		arg := strings.TrimLeft(flagArgs[i], "-")
+		arg = strings.TrimPrefix(arg, "test.")
+		switch {
+		case arg == name && goValueFlags[name] && i+1 < len(flagArgs):
+			i++
+			value, found = flagArgs[i], true
+		case arg == name:
+			value, found = "true", true
+		case strings.HasPrefix(arg, name+"="):
+			value, found = strings.TrimPrefix(arg, name+"="), true
+		}
+	}
+	return value, found
+}
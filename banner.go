@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// printBanner prints the toolchain and environment the tests
+// are about to run in, given the go test args.
+func printBanner(args []string) {
+	version, _ := exec.Command("go", "version").Output()
+	goenv, _ := exec.Command("go", "env", "GOOS", "GOARCH").Output()
+	wd, _ := os.Getwd()
+	flagArgs, _, _ := splitArgs(args)
+	wrapper, _ := flagValue(flagArgs, "exec")
+	for _, l := range bannerLines(string(version), string(goenv), wd, wrapper) {
+		color.New(neutral).Println(l)
+	}
+}
+
+// bannerLines formats the output of go version and
+// go env GOOS GOARCH along with the working directory
+// and the -exec wrapper the tests run under, if any.
+func bannerLines(version, goenv, wd, wrapper string) []string {
+	var lines []string
+	if v := strings.TrimSpace(version); v != "" {
+		lines = append(lines, v)
+	}
+	if f := strings.Fields(goenv); len(f) == 2 {
+		lines = append(lines, "GOOS/GOARCH: "+f[0]+"/"+f[1])
+	}
+	if wd != "" {
+		lines = append(lines, "Working directory: "+wd)
+	}
+	if wrapper != "" {
+		lines = append(lines, "Exec wrapper: "+wrapper)
+	}
+	return lines
+}
+
+// verdictWidth is the width of the -banner box.
+const verdictWidth = 40
+
+// verdict returns the word and color of the -banner: green if
+// every test passed, yellow if some were skipped or failed but
+// are known to be flaky, and red if tests or the run failed.
+func (r *ResultSummary) verdict() (string, color.Attribute) {
+	c := r.snapshot()
+	switch {
+	case c.fail > 0 || r.goErrors > 0 || r.races > 0 || r.forbidden > 0:
+		return "FAILED", fail
+	case r.flaky > 0:
+		return "PASSED WITH FLAKY FAILURES", skip
+	case c.skipped > 0:
+		return "PASSED WITH SKIPS", skip
+	}
+	return "PASSED", pass
+}
+
+// printVerdict prints the verdict of the run in a box.
+func (r *ResultSummary) printVerdict() {
+	word, c := r.verdict()
+	line := strings.Repeat("=", verdictWidth)
+	pad := (verdictWidth - len(word)) / 2
+	b := color.New(c, color.Bold)
+	b.Println(line)
+	b.Println(strings.Repeat(" ", pad) + word)
+	b.Println(line)
+}
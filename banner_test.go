@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecWrapper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "gotestrc")
+	if err := ioutil.WriteFile(config, []byte("-env-banner -exec 'qemu-arm -L /usr/arm-linux-gnueabi'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// go test prints every argument it gets on a line of its own.
+	script := `case "$1" in
+version) echo 'go version go1.22.0 linux/arm' ;;
+env) printf 'linux\narm\n' ;;
+test) shift; for arg in "$@"; do echo "arg: $arg"; done ;;
+esac
+`
+	const wrapper = "qemu-arm -L /usr/arm-linux-gnueabi"
+	for _, tt := range []struct {
+		name   string
+		config string
+		args   []string
+		want   []string
+	}{
+		{
+			name: "separate value",
+			args: []string{"-env-banner", "-exec", wrapper, "-run", "TestA", "./..."},
+			want: []string{"-exec", wrapper, "-run", "TestA", "./..."},
+		},
+		{
+			name: "joined value",
+			args: []string{"-env-banner", "-exec=" + wrapper, "./..."},
+			want: []string{"-exec=" + wrapper, "./..."},
+		},
+		{
+			name:   "quoted in the config file",
+			config: config,
+			args:   []string{"./..."},
+			want:   []string{"-exec", wrapper, "./..."},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never"}, tt.args...)...)
+			defer cleanup()
+			if tt.config != "" {
+				cmd.Env = append(cmd.Env, configEnv+"="+tt.config)
+			}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(string(out), "\n") {
+				if strings.HasPrefix(line, "arg: ") {
+					got = append(got, strings.TrimPrefix(line, "arg: "))
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("go test got args %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(string(out), "\nExec wrapper: "+wrapper+"\n") {
+				t.Errorf("banner doesn't show the wrapper:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestEnvBanner(t *testing.T) {
+	script := `case "$1" in
+version) echo 'go version go1.22.0 linux/arm' ;;
+env) printf 'linux\narm\n' ;;
+test) printf -- '--- PASS: TestA (0.00s)\nok  \texample.com/a\t0.010s\n' ;;
+esac
+`
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	banner := "go version go1.22.0 linux/arm\nGOOS/GOARCH: linux/arm\nWorking directory: " + wd + "\n"
+	for _, tt := range []struct {
+		args   []string
+		banner bool
+	}{
+		{args: []string{"-env-banner"}, banner: true},
+		{args: nil, banner: false},
+	} {
+		cmd, cleanup := stubGo(t, script, append([]string{"-color", "never"}, tt.args...)...)
+		defer cleanup()
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.HasPrefix(string(out), banner); got != tt.banner {
+			t.Errorf("%q: output starts with the banner = %v, want %v:\n%s", tt.args, got, tt.banner, out)
+		}
+		if !strings.Contains(string(out), "--- PASS: TestA (0.00s)\n") {
+			t.Errorf("%q: output lacks the test results:\n%s", tt.args, out)
+		}
+	}
+}
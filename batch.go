@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// runBatches expands the packages in args with go list and runs
+// go test on them in n batches, one after the other. If n is 0,
+// every package is tested on its own, so that one failing to
+// build can't keep the others from being tested. It returns the
+// first nonzero exit status of the batches, if any.
+func runBatches(args []string, n int, summary *ResultSummary) int {
+	flagArgs, pkgs, testArgs := splitArgs(args)
+	pkgs, err := goList(flagArgs, pkgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	code := 0
+	batches := partition(pkgs, len(pkgs))
+	if n > 0 {
+		batches = partition(pkgs, n)
+	}
+	for i, b := range batches {
+		if n > 0 {
+			color.New(neutral).Printf("Batch %d/%d: %d packages\n", i+1, len(batches), len(b))
+		}
+		batchArgs := append(append(append([]string{}, flagArgs...), b...), testArgs...)
+		if c := run(batchArgs, summary); code == 0 {
+			code = c
+		}
+		if isBrokenPipe(summary.writeErr) || summary.stoppedBy != "" {
+			break
+		}
+	}
+	return code
+}
+
+// goListFlags are the build flags that affect which packages
+// go list matches.
+var goListFlags = []string{"tags", "mod", "modfile"}
+
+// goList returns the import paths of the packages matching pkgs.
+// Packages with errors are listed too, for go test to report.
+func goList(flagArgs, pkgs []string) ([]string, error) {
+	args := []string{"list", "-e"}
+	for _, name := range goListFlags {
+		if v, ok := flagValue(flagArgs, name); ok {
+			args = append(args, "-"+name+"="+v)
+		}
+	}
+	args = append(args, pkgs...)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// partition splits pkgs into at most n batches of about
+// the same size, keeping their order.
+func partition(pkgs []string, n int) [][]string {
+	if n > len(pkgs) {
+		n = len(pkgs)
+	}
+	var batches [][]string
+	for i := 0; i < n; i++ {
+		lo, hi := i*len(pkgs)/n, (i+1)*len(pkgs)/n
+		batches = append(batches, pkgs[lo:hi])
+	}
+	return batches
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	pkgs := []string{"a", "b", "c", "d", "e"}
+	for _, tt := range []struct {
+		n    int
+		want string
+	}{
+		{1, "[[a b c d e]]"},
+		{2, "[[a b] [c d e]]"},
+		{3, "[[a] [b c] [d e]]"},
+		{5, "[[a] [b] [c] [d] [e]]"},
+		{8, "[[a] [b] [c] [d] [e]]"},
+	} {
+		if got := fmt.Sprint(partition(pkgs, tt.n)); got != tt.want {
+			t.Errorf("partition(%v, %d) = %s, want %s", pkgs, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Every go test records the packages it tests. Those of the
+	// first batch pass, and example.com/c in the second fails.
+	batches := filepath.Join(dir, "batches")
+	script := `case "$1" in
+list) for p in a b c d e; do echo example.com/$p; done ;;
+test)
+	shift
+	echo "$@" >> ` + batches + `
+	code=0
+	for pkg in "$@"; do
+		case "$pkg" in
+		example.com/c) printf -- '--- FAIL: TestC (0.00s)\nFAIL\nFAIL\t%s\t0.010s\n' "$pkg"; code=1 ;;
+		*) printf -- '--- PASS: TestA (0.00s)\nok  \t%s\t0.010s\n' "$pkg" ;;
+		esac
+	done
+	exit $code ;;
+esac
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-batch", "2", "./...")
+	defer cleanup()
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	b, err := ioutil.ReadFile(batches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "example.com/a example.com/b\nexample.com/c example.com/d example.com/e\n"; got != want {
+		t.Errorf("go test ran on:\n%s\nwant:\n%s", got, want)
+	}
+	for _, want := range []string{"Batch 1/2: 2 packages\n", "Batch 2/2: 3 packages\n", "\nPackages: 5\n", "\nPASS: 8\n", "\nFAIL: 3\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	// There is a single summary, after the last batch.
+	if n := strings.Count(string(out), "Summary:"); n != 1 {
+		t.Errorf("output has %d summaries, want 1:\n%s", n, out)
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// example.com/b fails to build, which would stop a single
+	// go test before it tests the others.
+	tested := filepath.Join(dir, "tested")
+	script := `case "$1" in
+list) for p in a b c; do echo example.com/$p; done ;;
+test)
+	shift
+	echo "$@" >> ` + tested + `
+	case "$1" in
+	example.com/b)
+		echo '# example.com/b'
+		echo 'b/b.go:3:1: syntax error: non-declaration statement outside function body'
+		printf 'FAIL\texample.com/b [build failed]\nFAIL\n'
+		exit 1 ;;
+	*) printf -- '--- PASS: TestA (0.00s)\nok  \t%s\t0.010s\n' "$1" ;;
+	esac ;;
+esac
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-keep-going", "./...")
+	defer cleanup()
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	b, err := ioutil.ReadFile(tested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "example.com/a\nexample.com/b\nexample.com/c\n"; got != want {
+		t.Errorf("go test ran on:\n%s\nwant one package at a time:\n%s", got, want)
+	}
+	for _, want := range []string{"ok  \texample.com/a\t0.010s\n", "FAIL\texample.com/b [build failed]\n", "ok  \texample.com/c\t0.010s\n", "\nPackages: 3\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "Batch ") {
+		t.Errorf("output has batch headers:\n%s", out)
+	}
+}
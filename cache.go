@@ -0,0 +1,106 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// isCached reports whether a trimmed package result line
+// reports results from the test cache, whose tests didn't run.
+func isCached(trimmed string) bool {
+	return strings.HasSuffix(trimmed, "(cached)")
+}
+
+// rerunCached tests the packages whose results came from the
+// test cache again, bypassing it, and returns the exit status.
+func rerunCached(args []string, summary *ResultSummary) int {
+	pkgs := summary.cachedPackages
+	if len(pkgs) == 0 {
+		return 0
+	}
+	color.New(color.FgCyan).Printf("Rerunning %d cached packages with -count=1:\n", len(pkgs))
+	// Only the rerun counts, as the cached results were forgotten.
+	summary.cachedPackages = nil
+	flagArgs, _, testArgs := splitArgs(args)
+	rerunArgs := append(append([]string{}, flagArgs...), "-count=1")
+	rerunArgs = append(append(rerunArgs, pkgs...), testArgs...)
+	return run(rerunArgs, summary)
+}
+
+// checkpoint is what a summary recorded up to the end of a
+// package, from which the results of the next one can be told
+// apart.
+type checkpoint struct {
+	counts
+	flaky          int
+	tests          int
+	histogram      []int
+	statusElapsed  map[string]time.Duration
+	slowest        string
+	slowestElapsed time.Duration
+}
+
+// saveCheckpoint records the end of a package with -rerun-cached.
+func (r *ResultSummary) saveCheckpoint() {
+	c := checkpoint{
+		counts:         counts{r.pass, r.skipped, r.fail},
+		flaky:          r.flaky,
+		tests:          len(r.tests),
+		histogram:      append([]int(nil), r.histogram...),
+		statusElapsed:  make(map[string]time.Duration),
+		slowest:        r.slowest,
+		slowestElapsed: r.slowestElapsed,
+	}
+	for k, v := range r.statusElapsed {
+		c.statusElapsed[k] = v
+	}
+	r.checkpoint = c
+}
+
+// forgetPackage forgets the results of the package whose output
+// is over, since the last checkpoint. -rerun-cached forgets the
+// cached results of packages, which the rerun reports again.
+func (r *ResultSummary) forgetPackage() {
+	c := r.checkpoint
+	r.pass, r.skipped, r.fail = c.pass, c.skipped, c.fail
+	r.flaky = c.flaky
+	for _, t := range r.tests[c.tests:] {
+		delete(r.output, t.Name)
+	}
+	r.tests = r.tests[:c.tests]
+	r.pkgStart = len(r.tests)
+	if r.histogram != nil {
+		r.histogram = append(c.histogram, make([]int, len(r.histogram)-len(c.histogram))...)
+	}
+	if r.statusElapsed != nil {
+		r.statusElapsed = c.statusElapsed
+	}
+	r.slowest, r.slowestElapsed = c.slowest, c.slowestElapsed
+	r.pkgFailures, r.pkgFlaky, r.pkgNoMatch = 0, 0, false
+	r.pkgFailed = nil
+}
+
+// cacheHitRate returns how many of the packages with tests
+// had their results come from the test cache, out of how many,
+// and the percentage, rounded.
+func (r *ResultSummary) cacheHitRate() (cached, total, percent int) {
+	cached, total = len(r.cachedPackages), r.testedPackages
+	if total == 0 {
+		return cached, total, 0
+	}
+	return cached, total, (cached*100 + total/2) / total
+}
+
+// printCached notes that the tests of cached packages didn't run.
+func (r *ResultSummary) printCached() {
+	if len(r.cachedPackages) == 0 {
+		return
+	}
+	color.Yellow("Cached: %d packages, whose tests didn't run. Use -nocache or -rerun-cached to run them.", len(r.cachedPackages))
+}
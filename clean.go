@@ -0,0 +1,48 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+)
+
+// cleanPreset are the flags -clean sets, with their values.
+var cleanPreset = map[string]string{
+	"hide-module-noise": "true",
+	"collapse-blanks":   "true",
+	"hide-faster-than":  "1ms",
+}
+
+// applyClean sets the flags of the -clean preset, except those
+// set on the command line or in the config file, and hides the
+// packages without test files unless GOTEST_SKIPNOTESTS says
+// otherwise. "=== RUN" lines are hidden anyway.
+func applyClean() {
+	if !*clean {
+		return
+	}
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, value := range cleanPreset {
+		if !set[name] {
+			flags.Set(name, value)
+		}
+	}
+	if os.Getenv(skipNoTestsEnv) == "" {
+		skipnotest = true
+	}
+}
+
+// isRepeatedBlank reports whether a trimmed line is blank and
+// follows another blank line, for -collapse-blanks.
+func (r *ResultSummary) isRepeatedBlank(trimmed string) bool {
+	if !*collapseBlanks {
+		return false
+	}
+	repeated := trimmed == "" && r.blank
+	r.blank = trimmed == ""
+	return repeated
+}
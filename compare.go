@@ -0,0 +1,131 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// outcomes maps tests, qualified by their package, to
+// their status the last time they ran.
+type outcomes map[string]string
+
+// outcomesPath returns where the outcomes of the tests run
+// in the working directory are kept.
+func outcomesPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gotest", fmt.Sprintf("outcomes-%x.json", sha1.Sum([]byte(wd)))), nil
+}
+
+// loadOutcomes reads the outcomes kept by the previous run.
+// There are none before the first run.
+func loadOutcomes(path string) (outcomes, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return outcomes{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var o outcomes
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return o, nil
+}
+
+// saveOutcomes updates the outcomes kept at path with the
+// results of this run. Tests that didn't run keep theirs.
+func saveOutcomes(path string, previous outcomes, tests []testResult) error {
+	for _, t := range tests {
+		previous[qualifiedName(t)] = t.Status
+	}
+	b, err := json.MarshalIndent(previous, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// comparison is how the results of a run compare to those
+// of the previous one.
+type comparison struct {
+	newlyFailing, newlyPassing, stillFailing []string
+}
+
+// compare sorts the tests that failed or passed into those
+// that failed for the first time, passed after failing last
+// time, and failed again.
+func compare(previous outcomes, tests []testResult) *comparison {
+	c := &comparison{}
+	for _, t := range tests {
+		name := qualifiedName(t)
+		failedBefore := previous[name] == "FAIL"
+		switch {
+		case t.Status == "FAIL" && failedBefore:
+			c.stillFailing = append(c.stillFailing, name)
+		case t.Status == "FAIL":
+			c.newlyFailing = append(c.newlyFailing, name)
+		case t.Status == "PASS" && failedBefore:
+			c.newlyPassing = append(c.newlyPassing, name)
+		}
+	}
+	return c
+}
+
+// compareWithPrevious compares the results of the run to those
+// of the previous one, and keeps them for the next.
+func (r *ResultSummary) compareWithPrevious() {
+	path, err := outcomesPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "comparing with the previous run: %v\n", err)
+		return
+	}
+	previous, err := loadOutcomes(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "comparing with the previous run: %v\n", err)
+		return
+	}
+	r.comparison = compare(previous, r.tests)
+	if err := saveOutcomes(path, previous, r.tests); err != nil {
+		fmt.Fprintf(os.Stderr, "keeping the results for the next run: %v\n", err)
+	}
+}
+
+// printComparison prints the tests whose outcome changed since
+// the previous run, and those that failed again.
+func (r *ResultSummary) printComparison() {
+	c := r.comparison
+	if c == nil {
+		return
+	}
+	if len(c.newlyFailing) > 0 {
+		color.Red("Newly failing: %s", strings.Join(trimPackages(c.newlyFailing), ", "))
+	}
+	if len(c.newlyPassing) > 0 {
+		color.Green("Newly passing: %s", strings.Join(trimPackages(c.newlyPassing), ", "))
+	}
+	if len(c.stillFailing) > 0 {
+		color.Yellow("Still failing: %s", strings.Join(trimPackages(c.stillFailing), ", "))
+	}
+}
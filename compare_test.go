@@ -0,0 +1,140 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	previous := outcomes{
+		"example.com/a.TestStillFailing": "FAIL",
+		"example.com/a.TestFixed":        "FAIL",
+		"example.com/a.TestBroken":       "PASS",
+		"example.com/b.TestSkipped":      "FAIL",
+	}
+	tests := []testResult{
+		{Package: "example.com/a", Name: "TestStillFailing", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestFixed", Status: "PASS"},
+		{Package: "example.com/a", Name: "TestBroken", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestNew", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestStillPassing", Status: "PASS"},
+		{Package: "example.com/b", Name: "TestSkipped", Status: "SKIP"},
+	}
+	got := compare(previous, tests)
+	want := &comparison{
+		newlyFailing: []string{"example.com/a.TestBroken", "example.com/a.TestNew"},
+		newlyPassing: []string{"example.com/a.TestFixed"},
+		stillFailing: []string{"example.com/a.TestStillFailing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compare() = %+v, want %+v", got, want)
+	}
+}
+
+func TestOutcomesKept(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gotest", "outcomes.json")
+
+	// There are no outcomes before the first run.
+	o, err := loadOutcomes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o) != 0 {
+		t.Fatalf("outcomes before the first run = %v, want none", o)
+	}
+	first := []testResult{
+		{Package: "example.com/a", Name: "TestA", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestB", Status: "PASS"},
+	}
+	if err := saveOutcomes(path, o, first); err != nil {
+		t.Fatal(err)
+	}
+	// Tests that didn't run the second time keep their outcome.
+	o, err = loadOutcomes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveOutcomes(path, o, []testResult{{Package: "example.com/a", Name: "TestA", Status: "PASS"}}); err != nil {
+		t.Fatal(err)
+	}
+	o, err = loadOutcomes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := outcomes{"example.com/a.TestA": "PASS", "example.com/a.TestB": "PASS"}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("outcomes = %v, want %v", o, want)
+	}
+}
+
+func TestOutcomesCorrupt(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{not json")
+	f.Close()
+	if _, err := loadOutcomes(f.Name()); err == nil {
+		t.Error("loadOutcomes succeeded with a corrupt file")
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the cache directory is not under HOME")
+	}
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runs := []struct {
+		output string
+		want   []string
+	}{
+		{
+			output: "--- FAIL: TestA (0.00s)\n--- FAIL: TestB (0.00s)\n--- PASS: TestC (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\n",
+			want:   []string{"Newly failing: example.com/x.TestA, example.com/x.TestB\n"},
+		},
+		{
+			output: "--- FAIL: TestA (0.00s)\n--- PASS: TestB (0.00s)\n--- FAIL: TestC (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\n",
+			want: []string{
+				"Newly failing: example.com/x.TestC\n",
+				"Newly passing: example.com/x.TestB\n",
+				"Still failing: example.com/x.TestA\n",
+			},
+		},
+	}
+	for i, run := range runs {
+		stream := filepath.Join(dir, "stream.txt")
+		if err := ioutil.WriteFile(stream, []byte(run.output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := gotestCmd("-color", "never", "-compare", "-dry-parse", stream)
+		cmd.Env = append(cmd.Env, "HOME="+dir, "XDG_CACHE_HOME="+filepath.Join(dir, "cache"))
+		out, err := cmd.Output()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Errorf("run %d: exit code = %d, want 1", i+1, code)
+		}
+		for _, want := range run.want {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("run %d: output lacks %q:\n%s", i+1, want, out)
+			}
+		}
+	}
+}
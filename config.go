@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// configName is the name of the config file searched for in
+// the working directory and its parents.
+const configName = ".gotestrc"
+
+// configPath returns the path of the config file, or "" if there
+// is none. GOTEST_CONFIG takes precedence over the config files
+// in the working directory, its parents, and the user config
+// directory, in that order.
+func configPath() (string, error) {
+	if p := os.Getenv(configEnv); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("%s: %v", configEnv, err)
+		}
+		return p, nil
+	}
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			p := filepath.Join(dir, configName)
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		p := filepath.Join(dir, "gotest", "config")
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// loadConfig returns the default arguments listed in the config
+// file. Arguments are separated by whitespace unless quoted, as
+// in -exec 'qemu-arm -L /usr/arm-linux-gnueabi', and lines
+// starting with # are ignored.
+func loadConfig() ([]string, error) {
+	p, err := configPath()
+	if err != nil || p == "" {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitQuoted(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		args = append(args, fields...)
+	}
+	return args, s.Err()
+}
+
+// splitQuoted splits line into fields separated by whitespace,
+// except within single or double quotes, which are removed.
+func splitQuoted(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, inField = c, true
+		case unicode.IsSpace(c):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The working directory has a config file of its own.
+	if err := ioutil.WriteFile(filepath.Join(dir, configName), []byte("-v\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	override := filepath.Join(dir, "ci.gotestrc")
+	if err := ioutil.WriteFile(override, []byte("# CI\n-race -exec 'qemu-arm -L /usr/arm-linux-gnueabi'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	env, set := os.LookupEnv(configEnv)
+	defer func() {
+		if set {
+			os.Setenv(configEnv, env)
+		} else {
+			os.Unsetenv(configEnv)
+		}
+	}()
+
+	for _, tt := range []struct {
+		name string
+		env  string
+		args []string
+		err  string
+	}{
+		{name: "working directory", args: []string{"-v"}},
+		{name: "override", env: override, args: []string{"-race", "-exec", "qemu-arm -L /usr/arm-linux-gnueabi"}},
+		{name: "missing", env: filepath.Join(dir, "missing"), err: configEnv + ": "},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(configEnv, tt.env)
+			args, err := loadConfig()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("loadConfig: %v", err)
+			case tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)):
+				t.Fatalf("loadConfig error = %v, want one starting with %q", err, tt.err)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("loadConfig = %q, want %q", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestConfigOverrideMissing(t *testing.T) {
+	cmd := gotestCmd("./...")
+	cmd.Env = append(cmd.Env, configEnv+"="+filepath.Join("testdata", "missing.gotestrc"))
+	out, err := cmd.CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if !strings.Contains(string(out), configEnv+": ") || !strings.Contains(string(out), "missing.gotestrc") {
+		t.Errorf("output doesn't report the missing config file:\n%s", out)
+	}
+}
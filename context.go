@@ -0,0 +1,50 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// failContext returns the last n lines of output printed before
+// a test failed, leaving out the headers and results of
+// tests, such as those of subtests.
+func failContext(output []string, n int) []string {
+	var lines []string
+	for _, line := range output {
+		if testName(strings.TrimSpace(line)) == "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// printFailContext prints the last -fail-context lines each
+// failed test printed before it failed, to see why at a glance.
+func (r *ResultSummary) printFailContext() {
+	header := false
+	for _, t := range r.tests {
+		if t.Status != "FAIL" {
+			continue
+		}
+		lines := failContext(t.Output, *failContextLines)
+		if len(lines) == 0 {
+			continue
+		}
+		if !header {
+			color.Cyan("Failure context:")
+			header = true
+		}
+		color.Red("  %s:", trimPackage(qualifiedName(t)))
+		for _, line := range lines {
+			color.White("  %s", mapPaths(line))
+		}
+	}
+}
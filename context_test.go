@@ -0,0 +1,35 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailContext(t *testing.T) {
+	output := []string{
+		"=== RUN   TestA",
+		"    a_test.go:3: one",
+		"=== RUN   TestA/sub",
+		"    --- PASS: TestA/sub (0.00s)",
+		"    a_test.go:5: two",
+		"    a_test.go:6: three",
+		"--- FAIL: TestA (0.00s)",
+	}
+	for _, tt := range []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"    a_test.go:6: three"}},
+		{2, []string{"    a_test.go:5: two", "    a_test.go:6: three"}},
+		// The headers and results of tests aren't context.
+		{10, []string{"    a_test.go:3: one", "    a_test.go:5: two", "    a_test.go:6: three"}},
+	} {
+		if got := failContext(output, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("failContext(output, %d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// slowTest is how long a test runs before -detail lists it
+// as slow.
+const slowTest = time.Second
+
+// printDetail lists the failed, skipped and slow tests.
+func (r *ResultSummary) printDetail() {
+	var failed, skipped, slow []testResult
+	for _, t := range r.tests {
+		switch t.Status {
+		case "FAIL":
+			failed = append(failed, t)
+		case "SKIP":
+			skipped = append(skipped, t)
+		}
+		if t.Elapsed >= slowTest {
+			slow = append(slow, t)
+		}
+	}
+	sort.SliceStable(slow, func(i, j int) bool {
+		return slow[i].Elapsed > slow[j].Elapsed
+	})
+
+	if len(failed) > 0 {
+		color.Cyan("Failures:")
+		for _, t := range failed {
+			color.Red("  %s", trimPackage(qualifiedName(t)))
+		}
+	}
+	if len(skipped) > 0 {
+		color.Cyan("Skips:")
+		for _, t := range skipped {
+			reason := t.Reason
+			if reason == "" {
+				reason = noReason
+			}
+			color.Yellow("  %s: %s", trimPackage(qualifiedName(t)), reason)
+		}
+	}
+	if len(slow) > 0 {
+		color.Cyan("Slow:")
+		for _, t := range slow {
+			color.White("  %s (%.2fs)", trimPackage(qualifiedName(t)), t.Elapsed.Seconds())
+		}
+	}
+}
+
+// qualifiedName returns the name of t qualified by its
+// package, if known.
+func qualifiedName(t testResult) string {
+	if t.Package == "" {
+		return t.Name
+	}
+	return t.Package + "." + t.Name
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// envDiff compares two environments as returned by os.Environ,
+// and returns the variables added, removed and changed between
+// them, sorted by name.
+func envDiff(before, after []string) (added, removed, changed []string) {
+	b, a := envMap(before), envMap(after)
+	for k, v := range a {
+		old, ok := b[k]
+		switch {
+		case !ok:
+			added = append(added, k+"="+v)
+		case old != v:
+			changed = append(changed, k+"="+v)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// envMap maps the names of the variables in env to their
+// values. Later values win, as when running a command.
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		m[kv[:i]] = kv[i+1:]
+	}
+	return m
+}
+
+// printEnvDiff prints how env, the environment of a command
+// gotest ran, differs from gotest's own.
+func printEnvDiff(env []string) {
+	added, removed, changed := envDiff(os.Environ(), env)
+	if len(added)+len(removed)+len(changed) == 0 {
+		return
+	}
+	color.Cyan("Environment of go test:")
+	for _, kv := range added {
+		color.Green("  + %s", kv)
+	}
+	for _, k := range removed {
+		color.Red("  - %s", k)
+	}
+	for _, kv := range changed {
+		color.Yellow("  ~ %s", kv)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvDiff(t *testing.T) {
+	before := []string{"HOME=/home/gopher", "GOFLAGS=-mod=mod", "TMPDIR=/tmp", "PATH=/usr/bin"}
+	after := []string{"HOME=/home/gopher", "GOFLAGS=-mod=vendor", "PATH=/usr/bin", "DB_URL=postgres://localhost", "CI=true"}
+	added, removed, changed := envDiff(before, after)
+	if want := []string{"CI=true", "DB_URL=postgres://localhost"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %q, want %q", added, want)
+	}
+	if want := []string{"TMPDIR"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %q, want %q", removed, want)
+	}
+	if want := []string{"GOFLAGS=-mod=vendor"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+	if added, removed, changed := envDiff(before, before); added != nil || removed != nil || changed != nil {
+		t.Errorf("envDiff of the same environment = %q, %q, %q, want nothing", added, removed, changed)
+	}
+}
+
+func TestEnvDiffBefore(t *testing.T) {
+	// go test sees what -before exported, and so does -after.
+	script := "echo \"=== RUN   TestDB$DB_PORT\"\necho \"--- PASS: TestDB$DB_PORT (0.00s)\"\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v", "-env-diff",
+		"-before", `echo DB_PORT=5432 >> "$GOTEST_ENV"; echo '# comment' >> "$GOTEST_ENV"`,
+		"-after", `echo "teardown $DB_PORT"`)
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--- PASS: TestDB5432", "\nEnvironment of go test:\n  + DB_PORT=5432\n", "\nteardown 5432\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// Without -env-diff, nothing is reported.
+	cmd, cleanup = stubGo(t, script, "-color", "never", "-v", "-before", `echo DB_PORT=5432 >> "$GOTEST_ENV"`)
+	defer cleanup()
+	out, err = cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "Environment of go test:") {
+		t.Errorf("environment reported without -env-diff:\n%s", out)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+)
+
+// liveEvent is a result sent to -events-socket as it happens.
+// The package of tests is only known with -json.
+type liveEvent struct {
+	Type    string  `json:"type"` // test or package
+	Package string  `json:"package,omitempty"`
+	Test    string  `json:"test,omitempty"`
+	Status  string  `json:"status"` // PASS, FAIL or SKIP
+	Elapsed float64 `json:"elapsed,omitempty"`
+	RunID   string  `json:"run_id"`
+}
+
+// summaryEvent is the last event sent to -events-socket.
+type summaryEvent struct {
+	Type string `json:"type"` // summary
+	jsonSummary
+}
+
+// packageStatuses maps the status of package result
+// lines to that of events.
+var packageStatuses = map[string]string{"ok": "PASS", "FAIL": "FAIL", "?": "SKIP"}
+
+// eventsSocket streams events as NDJSON to a Unix socket, for
+// live UIs. Events are dropped while nothing listens on it.
+type eventsSocket struct {
+	path     string
+	conn     net.Conn
+	lastDial time.Time
+}
+
+// events is the -events-socket sink, if any.
+var events *eventsSocket
+
+// loadEvents sets up the -events-socket sink. The socket is
+// only connected to once there are events to send.
+func loadEvents() {
+	if *eventsSocketPath != "" {
+		events = &eventsSocket{path: *eventsSocketPath}
+	}
+}
+
+// sendEvent sends v to the -events-socket sink, if any.
+func sendEvent(v interface{}) {
+	if events != nil {
+		events.send(v)
+	}
+}
+
+// send writes v as a line of JSON. A UI that is not listening
+// or too slow to read must not hold up the run, so writes time
+// out, and the socket is dialed again at most once a second.
+func (s *eventsSocket) send(v interface{}) {
+	if s.conn == nil {
+		if time.Since(s.lastDial) < time.Second {
+			return
+		}
+		s.lastDial = time.Now()
+		conn, err := net.Dial("unix", s.path)
+		if err != nil {
+			return
+		}
+		s.conn = conn
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := s.conn.Write(append(b, '\n')); err != nil {
+		s.close()
+	}
+}
+
+func (s *eventsSocket) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
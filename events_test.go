@@ -0,0 +1,89 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventsSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+
+	lines := make(chan []string, 1)
+	go func() {
+		var got []string
+		defer func() { lines <- got }()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			var e struct {
+				Type, Test, Package, Status string
+				RunID                       string `json:"run_id"`
+			}
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				got = append(got, "bad JSON: "+s.Text())
+				continue
+			}
+			got = append(got, e.Type+" "+e.Status+" "+e.Package+e.Test+" "+e.RunID)
+		}
+	}()
+
+	script := "echo '--- PASS: TestA (0.00s)'\necho '--- FAIL: TestB (0.00s)'\necho 'FAIL'\necho 'FAIL\texample.com/x\t0.010s'\nexit 1\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v", "-events-socket", path, "-run-id", "run-42")
+	defer cleanup()
+	if code := exitCodeOf(t, cmd.Run()); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	// Every event carries the run ID.
+	want := []string{
+		"test PASS TestA run-42",
+		"test FAIL TestB run-42",
+		"package FAIL example.com/x run-42",
+		"summary   run-42",
+	}
+	select {
+	case got := <-lines:
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("events:\n%q\nwant:\n%q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the events socket wasn't closed")
+	}
+}
+
+func TestEventsSocketNotListening(t *testing.T) {
+	// Nothing listens, which must neither fail nor slow the run.
+	s := &eventsSocket{path: filepath.Join(os.TempDir(), "gotest-nobody.sock")}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		s.send(liveEvent{Type: "test", Test: "TestA", Status: "PASS"})
+	}
+	s.close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("sending to nobody took %v", d)
+	}
+}
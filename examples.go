@@ -0,0 +1,35 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// exampleDiff tracks the output a failed example printed, under
+// "got:", and the output it should have printed, under "want:".
+// It returns the color of a trimmed line in either block: that
+// of failed tests for got and that of passed tests for want.
+func (r *ResultSummary) exampleDiff(trimmed string) (color.Attribute, bool) {
+	switch {
+	case strings.HasPrefix(trimmed, "--- FAIL: Example"):
+		r.example = "failed"
+		return 0, false
+	case r.example == "failed" && trimmed == "got:":
+		r.example = "got"
+	case r.example == "got" && trimmed == "want:":
+		r.example = "want"
+	// The blocks end with the next test or package result.
+	case r.example == "" || marker(trimmed) != "" || strings.HasPrefix(trimmed, "=== "):
+		r.example = ""
+		return 0, false
+	}
+	if r.example == "want" {
+		return pass, true
+	}
+	return fail, true
+}
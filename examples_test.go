@@ -0,0 +1,31 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestExampleDiffColors(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-dry-parse", filepath.Join("testdata", "example.txt")).Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	colored := func(c color.Attribute, line string) string {
+		return fmt.Sprintf("\033[%dm%s\n\033[0m", c, line)
+	}
+	// What the example printed is in the color of failures, and
+	// what it should have printed in that of passes.
+	want := colored(fail, "got:") + colored(fail, "hello") + colored(fail, "world") +
+		colored(pass, "want:") + colored(pass, "hello") + colored(pass, "there")
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the colored got and want blocks %q:\n%q", want, out)
+	}
+}
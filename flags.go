@@ -0,0 +1,217 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// flags are gotest's own flags. Every other argument is
+// passed through to go test untouched.
+var flags = flag.NewFlagSet("gotest", flag.ContinueOnError)
+
+// hiddenFlags are for debugging gotest itself, and are left
+// out of the usage.
+var hiddenFlags = map[string]bool{"self-profile": true}
+
+// pathMapping is set by -map-path.
+var pathMapping pathMaps
+
+func init() {
+	flags.Usage = usage
+	flags.Var(&pathMapping, "map-path", "rewrite the paths under from to be under to, given as `from=to`, such as container paths to host paths; can be repeated")
+}
+
+// usage prints the flags that aren't hidden.
+func usage() {
+	visible := flag.NewFlagSet("gotest", flag.ContinueOnError)
+	visible.SetOutput(flags.Output())
+	flags.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	fmt.Fprintln(flags.Output(), "Usage of gotest:")
+	visible.PrintDefaults()
+}
+
+var (
+	jsonlInput    = flags.Bool("jsonl-input", false, "read a go test -json stream from stdin instead of running go test")
+	dryParse      = flags.String("dry-parse", "", "render the go test output, plain or -json, saved in `file` instead of running go test")
+	aggregate     = flags.Bool("aggregate", false, "render the go test output, plain or -json, saved in the files given as arguments, with a combined summary")
+	skipPreamble  = flags.Int("skip-preamble", 0, "ignore the first `n` lines of output, such as a banner printed by a CI wrapper")
+	failuresFile  = flags.String("failures-file", "", "write the output of failed tests to `file`")
+	jsonlFailures = flags.String("jsonl-failures", "", "write every failed test with its output as a line of JSON to `file`")
+	junitFile     = flags.String("junit", "", "write a JUnit XML report to `file`")
+	summaryJSON   = flags.String("summary-json", "", "write the summary as JSON to `file`")
+	htmlFile      = flags.String("html", "", "write a standalone HTML report to `file`")
+	markdownFile  = flags.String("summary-markdown", "", "write the summary as Markdown to `file`, for pull request comments")
+	stepSummary   = flags.Bool("step-summary", true, "append the Markdown summary to $GITHUB_STEP_SUMMARY on GitHub Actions")
+	outputDir     = flags.String("output-dir", "", "write the reports enabled with relative paths to `dir`")
+	failuresLast  = flags.Bool("failures-last", false, "print the output of failed tests at the end of the run")
+	sortOutput    = flags.Bool("sort-output", false, "hold back the output until the run is over and print it sorted by package and test")
+	focusFailures = flags.Bool("focus-failures", false, "print the output of packages that failed, but only the result line of those that passed")
+	tree          = flags.Bool("tree", false, "group subtests under their parent test, colored by the outcome of the group")
+	trimPrefix    = flags.String("trim-prefix", "", "leave `prefix` out of package names, or the path of the main module if it is \"module\"")
+	separators    = flags.Bool("separators", false, "print a separator between packages")
+	countsOnly    = flags.Bool("counts-only", false, "print only the summary, not the output of go test")
+	banner        = flags.Bool("banner", false, "start the summary with a PASSED or FAILED banner")
+	noSummary     = flags.Bool("no-summary", false, "don't print the summary")
+	summaryOnFail = flags.Bool("summary-on-fail", false, "print the summary only if tests failed or were skipped")
+
+	eventsSocketPath = flags.String("events-socket", "", "stream results as NDJSON to the Unix socket at `path`, for live UIs")
+	preambleEndFlag  = flags.String("preamble-end", "", "ignore the output up to and including the first line matching `regexp`")
+	runIDFlag        = flags.String("run-id", "", "`id` of the run in the events and the JSON summary, a random UUID by default")
+
+	clean           = flags.Bool("clean", false, "hide module noise, packages without test files and passes under 1ms, and collapse blank lines, unless set otherwise")
+	hideModuleNoise = flags.Bool("hide-module-noise", false, `hide "go: downloading" and similar module resolution messages`)
+	hideGoWarnings  = flags.Bool("hide-go-warnings", false, `hide "go: warning:" messages from the go command`)
+	collapseBlanks  = flags.Bool("collapse-blanks", false, "print runs of blank lines as a single one")
+	hidePassMarker  = flags.Bool("hide-pass-marker", false, `hide the bare "PASS" line printed before "ok" in verbose mode`)
+
+	showParallelMarkers = flags.Bool("show-parallel-markers", false, `show the "=== PAUSE", "=== CONT" and "=== NAME" markers of parallel tests, dimmed`)
+
+	maxLinesPerTest = flags.Int("max-lines-per-test", 0, "leave out the output of tests past `n` lines")
+	collapseStacks  = flags.Int("collapse-stacks", 0, "show only the first `k` frames of goroutine stacks, such as those of panics")
+	wrapLines       = flags.Bool("wrap", false, "wrap long lines at spaces to the terminal width, with a hanging indent")
+
+	topOutput   = flags.Int("top-output", 0, "list the `n` tests that printed the most output in the summary")
+	skipReasons = flags.Bool("skip-reasons", false, "color the reasons tests were skipped for and count them in the summary")
+	detail      = flags.Bool("detail", false, "list the failed, skipped and slow tests in the summary")
+	compareRuns = flags.Bool("compare", false, "list the tests newly failing, newly passing and still failing since the previous run")
+	grid        = flags.Bool("grid", false, "show the status of every package as a grid of symbols in the summary")
+
+	failContextLines = flags.Int("fail-context", 0, "print the last `n` lines each failed test printed before failing in the summary")
+
+	accessible = flags.Bool("accessible", false, "label results with [PASS], [FAIL] and [SKIP] and only color the summary")
+	colorScope = flags.String("color-scope", "line", "color whole result lines, or only their marker, such as --- FAIL: or ok")
+
+	colorByPackage = flags.Bool("color-by-package", false, "color the import paths of package result lines by package, the same in every run")
+
+	colorMode = flags.String("color", "auto", "whether to color the output: always, auto or never")
+	style     = flags.String("style", "full", "full colors whole lines, minimal only the packages that passed")
+	passColor = flags.String("pass-color", "", "`color` of passed tests, overriding GOTEST_PALETTE")
+	failColor = flags.String("fail-color", "", "`color` of failed tests, overriding GOTEST_PALETTE")
+	skipColor = flags.String("skip-color", "", "`color` of skipped tests")
+	rulesFile = flags.String("rules", "", "`file` of rules hiding or coloring the lines matching regular expressions")
+
+	verboseOnFail       = flags.Bool("verbose-on-fail", false, "rerun the first failed package with -v")
+	printFailedCommands = flags.Bool("print-failed-commands", false, "print a go test command to rerun only the failed tests of each package")
+
+	nocache         = flags.Bool("nocache", false, "bypass the test cache, like -count=1")
+	noCacheDisplay  = flags.Bool("no-cache-display", false, "note in the summary the packages whose results came from the test cache")
+	rerunCachedFlag = flags.Bool("rerun-cached", false, "test the packages whose results came from the test cache again, bypassing it")
+	parallel        = flags.Int("p", 0, "test up to `n` packages in parallel, passed on to go test and reported in the summary")
+
+	repeatUntilFail = flags.Bool("repeat-until-fail", false, "run the tests again and again until they fail, up to -repeat-max times")
+	repeatMax       = flags.Int("repeat-max", 100, "the most `times` to run the tests with -repeat-until-fail")
+
+	batch     = flags.Int("batch", 0, "split the packages into `n` batches and test them one batch at a time")
+	keepGoing = flags.Bool("keep-going", false, "test every package with its own go test, so one failing to build can't stop the others")
+	since     = flags.String("since", "", "only test packages with Go files changed since the git `ref`")
+
+	perTestTimeout = flags.Duration("per-test-timeout", 0, "list the tests that took longer than `d` in the summary, without stopping them")
+	hideFasterThan = flags.Duration("hide-faster-than", 0, "hide the results of tests that passed in less than `d`, still counting them")
+	redact         = flags.Bool("redact-durations", false, "print the durations of tests and packages as zero, for output that is the same every run")
+
+	histogram       = flags.Bool("histogram", false, "print a histogram of test durations in the summary")
+	elapsedByStatus = flags.Bool("elapsed-by-status", false, "print the time spent in passed, skipped and failed tests in the summary")
+	measureCompile  = flags.Bool("measure-compile-time", false, "estimate the time spent building tests rather than running them in the summary")
+	templateText    = flags.String("template", "", "text/template `template` for test result lines, with fields .Status, .Test, .Package and .Elapsed")
+
+	mergeSubtests = flags.Bool("merge-subtests", false, "count subtests as part of their parent test in the summary")
+
+	strict           = flags.Bool("strict", false, "fail the run if -run matches no tests in a package")
+	requireTests     = flags.Bool("require-tests", false, "fail the run if no tests ran at all")
+	allowNoTestFiles = flags.Bool("allow-no-test-files", false, "with -require-tests, pass runs in which no package had test files")
+	failOnOutputFlag = flags.String("fail-on-output", "", "fail the run if any output matches `regexp`")
+
+	flakyList         = flags.String("flaky-list", "", "`file` listing known flaky tests, whose failures don't fail the run")
+	maxFailedPackages = flags.Int("max-failed-packages", -1, "exit with 0 if no more than `n` packages failed")
+
+	stopOnPackageFail = flags.Bool("stop-on-package-fail", false, "stop go test once a package has failed, letting it finish first")
+
+	envBanner   = flags.Bool("env-banner", false, "print the go version, GOOS/GOARCH and working directory before running tests")
+	envDiffFlag = flags.Bool("env-diff", false, "print how the environment go test ran with differs from gotest's own, such as the variables -before exported")
+
+	liveTitle = flags.Bool("title", false, "show live pass and fail counts in the terminal title")
+
+	noSignalForward = flags.Bool("no-signal-forward", false, "don't relay signals gotest receives to go test")
+
+	hangTimeout = flags.Duration("hang-timeout", 0, "warn when go test prints nothing for `d`, as it may be hung")
+	hangAction  = flags.String("hang-action", "warn", "what to do after -hang-timeout: warn, or quit to also send SIGQUIT for a goroutine dump")
+
+	metricsURL   = flags.String("metrics-url", "", "push the final counts to a statsd:// server or an http:// Prometheus pushgateway `url`")
+	otlpEndpoint = flags.String("otlp-endpoint", "", "export every test as a span, under one per package, to the OTLP/HTTP collector at `url`")
+
+	webhookURL          = flags.String("webhook", "", "post the summary as JSON to `url` once the run is over")
+	webhookTemplateText = flags.String("webhook-template", "", "text/template `template` for the -webhook body, with the fields of -summary-json")
+
+	pipe      = flags.String("pipe", "", "also feed the raw output of go test to the shell `command`, such as a formatter")
+	tagStderr = flags.Bool("tag-stderr", false, "mark the lines go test writes to stderr, such as build errors")
+
+	separateStreams = flags.Bool("separate-streams", false, "keep the output go test writes to stderr apart, marked and printed after that of each package on stdout")
+
+	onStart = flags.String("on-start", "", "shell `command` to run once right before go test first starts, which is passed in GOTEST_COMMAND")
+	before  = flags.String("before", "", "shell `command` to set up the tests with before running them")
+	after   = flags.String("after", "", "shell `command` to tear down the tests with after running them, even if they fail")
+
+	footerCmd      = flags.String("footer-cmd", "", "shell `command` to run after the summary, such as a coverage report")
+	footerMustPass = flags.Bool("footer-must-pass", false, "fail the run if the -footer-cmd command fails")
+
+	selfProfile = flags.Bool("self-profile", false, "report the time spent parsing output and waiting for go test")
+)
+
+// parseFlags extracts gotest's own flags from args and returns
+// the remaining arguments for go test. Flags after -args belong
+// to the test binary and are never interpreted by gotest.
+func parseFlags(args []string) ([]string, error) {
+	var own, rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg || name == "" {
+			rest = append(rest, arg)
+			continue
+		}
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, hasValue = name[:j], true
+		}
+		f := flags.Lookup(name)
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		own = append(own, arg)
+		if !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+	if err := flags.Parse(own); err != nil {
+		return nil, err
+	}
+	// Like the flag package, report invalid values with the usage.
+	if *repeatMax < 1 {
+		err := fmt.Errorf("invalid value %d for flag -repeat-max: must be at least 1", *repeatMax)
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return nil, err
+	}
+	return rest, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// flakyTests are the tests loaded from -flaky-list.
+var flakyTests map[string]bool
+
+// loadFlakyList reads the -flaky-list file, which lists one
+// test name per line. Blank lines and lines starting with #
+// are ignored.
+func loadFlakyList() error {
+	if *flakyList == "" {
+		return nil
+	}
+	f, err := os.Open(*flakyList)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	flakyTests = make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		flakyTests[name] = true
+	}
+	return s.Err()
+}
+
+// isFlaky reports whether test, or a test it is a subtest
+// of, is known to be flaky.
+func isFlaky(test string) bool {
+	for test != "" {
+		if flakyTests[test] {
+			return true
+		}
+		i := strings.LastIndex(test, "/")
+		if i < 0 {
+			break
+		}
+		test = test[:i]
+	}
+	return false
+}
+
+// failedTest is a test counted as failed, which go test reports
+// before its subtests.
+type failedTest struct {
+	// n is how much the test counts towards the failures.
+	n int
+	// excused is set while the only subtests known to have
+	// failed are known flaky tests, so the test isn't counted.
+	excused bool
+	// failed is set once a subtest that isn't known to be
+	// flaky has failed, so the test counts whatever the others did.
+	failed bool
+}
+
+// parentTests returns the tests that test is a subtest of,
+// innermost first.
+func parentTests(test string) []string {
+	var parents []string
+	for i := strings.LastIndex(test, "/"); i >= 0; i = strings.LastIndex(test, "/") {
+		test = test[:i]
+		parents = append(parents, test)
+	}
+	return parents
+}
+
+// failTest records that test failed, counting n. Its parents
+// failed too, even those that known flaky subtests excused.
+func (r *ResultSummary) failTest(test string, n int) {
+	if r.pkgFailed == nil {
+		r.pkgFailed = make(map[string]*failedTest)
+	}
+	r.pkgFailed[test] = &failedTest{n: n}
+	for _, p := range parentTests(test) {
+		f, ok := r.pkgFailed[p]
+		if !ok {
+			continue
+		}
+		if f.excused {
+			r.fail += f.n
+			r.pkgFailures++
+			f.excused = false
+		}
+		f.failed = true
+	}
+}
+
+// excuseParents stops counting the failures of the parents of
+// the known flaky test, which may only have failed because it
+// did, unless another of their subtests failed.
+func (r *ResultSummary) excuseParents(test string) {
+	for _, p := range parentTests(test) {
+		f, ok := r.pkgFailed[p]
+		if !ok || f.failed || f.excused {
+			continue
+		}
+		r.fail -= f.n
+		r.pkgFailures--
+		f.excused = true
+	}
+}
+
+// failedOnlyByFlaky reports whether a trimmed FAIL line, which
+// go test prints before and in the result of a failed package
+// and at the end of the run, only reports failures of known
+// flaky tests. Such lines are no more failures than the tests.
+func (r *ResultSummary) failedOnlyByFlaky(trimmed string) bool {
+	if _, _, ok := packageResult(trimmed); ok {
+		// trackPackage has just recorded the package.
+		return len(r.pkgStatuses) > 0 && r.pkgStatuses[len(r.pkgStatuses)-1] == "flaky"
+	}
+	if r.pkgFlaky > 0 || r.pkgFailures > 0 {
+		return r.pkgFailures == 0
+	}
+	return len(r.failedPackages) == 0 && len(r.flakyPackages) > 0
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/fatih/color"
+
+// focusLine is a line of output held back by -focus-failures.
+type focusLine struct {
+	line, trimmed string
+	c             color.Attribute
+}
+
+// focus holds back the output of the package being tested
+// until its result line. The output is then printed if the
+// package failed, and left out if it passed, so that only
+// its result line is printed. It reports whether line was
+// held back or printed.
+func (r *ResultSummary) focus(line, trimmed string, c color.Attribute) bool {
+	status, _, ok := packageResult(trimmed)
+	if !ok {
+		r.focused = append(r.focused, focusLine{line, trimmed, c})
+		return true
+	}
+	if status == "FAIL" {
+		r.releaseFocus()
+	}
+	r.focused = nil
+	r.display(line, trimmed, c)
+	return true
+}
+
+// releaseFocus prints the output held back by -focus-failures,
+// such as that of a package that failed, or the output after
+// the last package at the end of the run.
+func (r *ResultSummary) releaseFocus() {
+	for _, l := range r.focused {
+		r.display(l.line, l.trimmed, l.c)
+	}
+	r.focused = nil
+}
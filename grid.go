@@ -0,0 +1,77 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// gridCell is how -grid shows a package of a given status.
+type gridCell struct {
+	symbol string
+	c      *color.Attribute
+}
+
+// gridCells maps the statuses of packages to their cells. The
+// colors are pointers as they may be changed by the flags.
+var gridCells = map[string]gridCell{
+	"ok":    {".", &pass},
+	"FAIL":  {"F", &fail},
+	"flaky": {"f", &skip},
+	"?":     {"-", &neutral},
+}
+
+// defaultWidth is the width of the terminal if unknown.
+const defaultWidth = 80
+
+// terminalWidth returns the width of the terminal, which
+// COLUMNS overrides.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := stdoutWidth(); n > 0 {
+		return n
+	}
+	return defaultWidth
+}
+
+// gridRows lays the statuses of packages out in rows of
+// at most width cells.
+func gridRows(statuses []string, width int) [][]string {
+	if width < 1 {
+		width = 1
+	}
+	var rows [][]string
+	for len(statuses) > width {
+		rows = append(rows, statuses[:width])
+		statuses = statuses[width:]
+	}
+	if len(statuses) > 0 {
+		rows = append(rows, statuses)
+	}
+	return rows
+}
+
+// printGrid prints the status of every package as a grid
+// of colored symbols, in the order they were tested.
+func (r *ResultSummary) printGrid() {
+	if len(r.pkgStatuses) == 0 {
+		return
+	}
+	color.Cyan("Package grid:")
+	for _, row := range gridRows(r.pkgStatuses, terminalWidth()) {
+		var b strings.Builder
+		for _, status := range row {
+			cell := gridCells[status]
+			b.WriteString(color.New(*cell.c).Sprint(cell.symbol))
+		}
+		color.Output.Write([]byte(b.String() + "\n"))
+	}
+}
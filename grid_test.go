@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGridRows(t *testing.T) {
+	statuses := []string{"ok", "ok", "FAIL", "?", "flaky"}
+	for _, tt := range []struct {
+		width int
+		want  string
+	}{
+		{1, "[[ok] [ok] [FAIL] [?] [flaky]]"},
+		{2, "[[ok ok] [FAIL ?] [flaky]]"},
+		{5, "[[ok ok FAIL ? flaky]]"},
+		{80, "[[ok ok FAIL ? flaky]]"},
+		{0, "[[ok] [ok] [FAIL] [?] [flaky]]"},
+	} {
+		if got := fmt.Sprint(gridRows(statuses, tt.width)); got != tt.want {
+			t.Errorf("gridRows(%v, %d) = %s, want %s", statuses, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	for _, tt := range []struct {
+		fixture string
+		args    []string
+		columns string
+		want    string
+		code    int
+	}{
+		{fixture: "cache.txt", columns: "3", want: "...\n-F.\n.\n", code: 1},
+		{fixture: "cache.txt", columns: "80", want: "...-F..\n", code: 1},
+		{fixture: "flaky.txt", args: []string{"-flaky-list", filepath.Join("testdata", "flaky.list")}, columns: "80", want: "f.\n"},
+	} {
+		t.Run(tt.fixture+"/"+tt.columns, func(t *testing.T) {
+			cmd := gotestCmd(append([]string{"-color", "never", "-grid", "-dry-parse", filepath.Join("testdata", tt.fixture)}, tt.args...)...)
+			cmd.Env = append(cmd.Env, "COLUMNS="+tt.columns)
+			out, err := cmd.Output()
+			if code := exitCodeOf(t, err); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if want := "\nPackage grid:\n" + tt.want; !strings.HasSuffix(string(out), want) {
+				t.Errorf("output doesn't end with:\n%s\ngot:\n%s", want, out)
+			}
+		})
+	}
+}
+
+func TestGridColors(t *testing.T) {
+	cmd := gotestCmd("-color", "always", "-grid", "-dry-parse", filepath.Join("testdata", "cache.txt"))
+	cmd.Env = append(cmd.Env, "COLUMNS=80")
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	// ok, no test files, then failed.
+	want := fmt.Sprintf("\033[%dm.\033[0m\033[%dm-\033[0m\033[%dmF\033[0m", pass, neutral, fail)
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the colored cells %q:\n%q", want, out)
+	}
+}
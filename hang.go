@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// watchdog warns when go test has printed nothing for
+// -hang-timeout, and with -hang-action quit sends SIGQUIT
+// to the tests so they dump the stacks of their goroutines.
+// It keeps watching after it fires.
+type watchdog struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	timeout time.Duration
+	signal  func(os.Signal) error
+}
+
+// startWatchdog starts a watchdog sending signals with signal.
+func startWatchdog(timeout time.Duration, signal func(os.Signal) error) *watchdog {
+	w := &watchdog{timeout: timeout, signal: signal}
+	w.mu.Lock()
+	w.timer = time.AfterFunc(timeout, w.fire)
+	w.mu.Unlock()
+	return w
+}
+
+func (w *watchdog) fire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer == nil {
+		return
+	}
+	if *hangAction == "quit" {
+		if err := w.signal(syscall.SIGQUIT); err != nil {
+			fmt.Fprintf(os.Stderr, "gotest: no output from go test for %v, and SIGQUIT couldn't be sent to dump goroutines: %v\n", w.timeout, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "gotest: no output from go test for %v, sent SIGQUIT to dump goroutines\n", w.timeout)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "gotest: no output from go test for %v, it may be hung\n", w.timeout)
+	}
+	w.timer.Reset(w.timeout)
+}
+
+// poke restarts the countdown, as go test printed something.
+func (w *watchdog) poke() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+// stop stops the watchdog for good.
+func (w *watchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer.Stop()
+	w.timer = nil
+}
+
+// watchedReader pokes a watchdog whenever output is read.
+type watchedReader struct {
+	r io.Reader
+	w *watchdog
+}
+
+func (r watchedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.w.poke()
+	}
+	return n, err
+}
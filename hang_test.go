@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHangQuit(t *testing.T) {
+	if _, err := exec.LookPath("pgrep"); err != nil {
+		t.Skip("pgrep is needed to find the tests")
+	}
+	// The stub test binary dumps its goroutines on SIGQUIT, which
+	// the stub go command must not get, or it would hold it back.
+	script := `trap 'echo "go test got SIGQUIT"' QUIT
+sh -c 'trap "echo goroutine dump; exit 2" QUIT; while :; do sleep 0.1; done'
+echo 'FAIL	example.com/x	1.000s'
+exit 1
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-hang-timeout", "500ms", "-hang-action", "quit")
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	for _, want := range []string{"sent SIGQUIT", "goroutine dump"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "go test got SIGQUIT") {
+		t.Errorf("go test was sent SIGQUIT:\n%s", out)
+	}
+}
+
+func TestHangQuitNoPgrep(t *testing.T) {
+	// The stub go command finds only sh and sleep, and no pgrep.
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"sh", "sleep"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			t.Skip(err)
+		}
+		if err := os.Symlink(path, filepath.Join(dir, name)); err != nil {
+			t.Skip(err)
+		}
+	}
+	script := "sleep 1\necho 'ok  \texample.com/x\t1.000s'\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-hang-timeout", "300ms", "-hang-action", "quit")
+	defer cleanup()
+	// The last PATH, which wins, starts with the stub.
+	path := cmd.Env[len(cmd.Env)-1]
+	stub := path[:strings.Index(path, string(os.PathListSeparator))]
+	cmd.Env = append(cmd.Env, stub+string(os.PathListSeparator)+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, out)
+	}
+	if want := "SIGQUIT couldn't be sent to dump goroutines: finding the tests with pgrep"; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+	if strings.Contains(string(out), "sent SIGQUIT") {
+		t.Errorf("SIGQUIT reported sent without pgrep:\n%s", out)
+	}
+}
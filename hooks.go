@@ -0,0 +1,175 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// shellCommand returns a command running cmdline in the shell.
+func shellCommand(cmdline string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", cmdline)
+	}
+	return exec.Command("sh", "-c", cmdline)
+}
+
+// hookEnv are the variables the -before command exported
+// through the file named by GOTEST_ENV, as KEY=VALUE lines.
+var hookEnv []string
+
+// childEnv returns the environment of the commands gotest
+// runs: its own, with the variables -before exported.
+func childEnv() []string {
+	return append(os.Environ(), hookEnv...)
+}
+
+// runHook runs cmdline in the shell, streaming its output,
+// with env added to its environment.
+func runHook(cmdline string, env ...string) error {
+	cmd := shellCommand(cmdline)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(childEnv(), env...)
+	return cmd.Run()
+}
+
+// runSetup runs the -before command, which can export variables
+// to go test and the other commands by appending KEY=VALUE lines
+// to the file named by GOTEST_ENV.
+func runSetup(cmdline string) error {
+	f, err := ioutil.TempFile("", "gotest-env")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	if err := runHook(cmdline, envFileEnv+"="+f.Name()); err != nil {
+		return err
+	}
+	env, err := readEnvFile(f.Name())
+	if err != nil {
+		return err
+	}
+	hookEnv = append(hookEnv, env...)
+	return nil
+}
+
+// readEnvFile reads the KEY=VALUE lines of the file at path,
+// skipping blank lines and those starting with #.
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var env []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.Index(line, "="); i <= 0 {
+			return nil, fmt.Errorf("%s: %q is not KEY=VALUE", envFileEnv, line)
+		}
+		env = append(env, line)
+	}
+	return env, s.Err()
+}
+
+// onStartErr is the error of -on-start, which only runs once.
+var (
+	onStartOnce sync.Once
+	onStartErr  error
+)
+
+// runOnStart runs -on-start before the first go test, given its
+// args, and returns the error it failed with, if any, every time.
+func runOnStart(args []string) error {
+	onStartOnce.Do(func() {
+		if *onStart == "" {
+			return
+		}
+		// Tell the hook what is about to run.
+		onStartErr = runHook(*onStart, "GOTEST_COMMAND="+commandLine(args))
+		if onStartErr != nil {
+			fmt.Fprintf(os.Stderr, "on-start command %q failed: %v\n", *onStart, onStartErr)
+		}
+	})
+	return onStartErr
+}
+
+// relaying is set while gotest relays the signals it receives
+// to go test, which then exits and lets the run finish.
+var relaying int32
+
+// teardownOnSignal runs teardown when gotest is stopped by a
+// signal it doesn't relay, and exits as the signal would have,
+// until the returned function is called.
+func teardownOnSignal(teardown func()) func() {
+	sigc := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigc, teardownSignals...)
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigc:
+				if atomic.LoadInt32(&relaying) > 0 {
+					continue
+				}
+				teardown()
+				os.Exit(128 + int(sig.(syscall.Signal)))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigc)
+		done <- struct{}{}
+	}
+}
+
+// commandLine formats args as a command line, quoting
+// the arguments that need it.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$|&;<>()*?[]#~") {
+			quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// runFooter runs the -footer-cmd command after the summary,
+// streaming its output, and returns the exit code of the run.
+// A failing footer command only fails the run with
+// -footer-must-pass.
+func runFooter(code int) int {
+	if *footerCmd == "" {
+		return code
+	}
+	if err := runHook(*footerCmd); err != nil {
+		fmt.Fprintf(os.Stderr, "footer command %q failed: %v\n", *footerCmd, err)
+		if *footerMustPass && code == 0 {
+			return 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOnStartOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	started := filepath.Join(dir, "started")
+
+	cmd, cleanup := stubGo(t, "echo 'ok  \texample.com/x\t0.010s'\n", "-color", "never",
+		"-repeat-until-fail", "-repeat-max", "3", "-on-start", `echo "$GOTEST_COMMAND" >> `+started, "./...")
+	defer cleanup()
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "go test -count=1 ./...\n"; got != want {
+		t.Errorf("-on-start ran with:\n%s\nwant once with:\n%s", got, want)
+	}
+}
+
+func TestFooterCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmd ends the footer output with CRLF")
+	}
+	for _, tt := range []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{args: []string{"-footer-cmd", "echo coverage: 81.0%"}, stdout: "Slowest test: TestA (0.00s)\ncoverage: 81.0%\n"},
+		{args: []string{"-footer-cmd", "exit 3"}, stderr: `footer command "exit 3" failed`},
+		{args: []string{"-footer-cmd", "exit 3", "-footer-must-pass"}, code: 1, stderr: `footer command "exit 3" failed`},
+	} {
+		cmd := gotestCmd(append([]string{"-color", "never", "-dry-parse", filepath.Join("testdata", "skip.txt")}, tt.args...)...)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if code := exitCodeOf(t, err); code != tt.code {
+			t.Errorf("%q: exit code = %d, want %d", tt.args, code, tt.code)
+		}
+		// The footer comes after the summary.
+		if !strings.HasSuffix(string(out), tt.stdout) {
+			t.Errorf("%q: output doesn't end with %q:\n%s", tt.args, tt.stdout, out)
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%q: stderr lacks %q:\n%s", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"strings"
+)
+
+// htmlReport writes a standalone HTML page to a file, with
+// a table of the tests of each package.
+type htmlReport struct {
+	path string
+}
+
+type htmlPage struct {
+	Summary  jsonSummary
+	Packages []htmlPackage
+}
+
+type htmlPackage struct {
+	Name  string
+	Tests []testResult
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotest report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.3em 0.8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+.badge { color: white; padding: 0.1em 0.5em; border-radius: 0.3em; font-size: 0.9em; }
+.pass { background: #2e7d32; }
+.fail { background: #c62828; }
+.skip { background: #f9a825; }
+pre { background: #f5f5f5; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>gotest report</h1>
+<p>
+Total: {{.Summary.Total}},
+<span class="badge pass">PASS {{.Summary.Pass}}</span>
+<span class="badge skip">SKIP {{.Summary.Skip}}</span>
+<span class="badge fail">FAIL {{.Summary.Fail}}</span>,
+in {{printf "%.2f" .Summary.Elapsed}}s
+</p>
+{{range .Packages}}
+<h2>{{if .Name}}{{.Name}}{{else}}Unknown package{{end}}</h2>
+<table>
+<tr><th>Test</th><th>Status</th><th>Time</th></tr>
+{{range .Tests}}
+<tr>
+<td>{{if .Output}}<details><summary>{{.Name}}</summary><pre>{{join .Output "\n"}}</pre></details>{{else}}{{.Name}}{{end}}</td>
+<td><span class="badge {{lower .Status}}">{{.Status}}</span></td>
+<td>{{printf "%.2f" .Elapsed.Seconds}}s</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+func (h htmlReport) report(summary *ResultSummary) error {
+	page := htmlPage{Summary: newJSONSummary(summary)}
+	index := make(map[string]int)
+	for _, t := range summary.tests {
+		i, ok := index[t.Package]
+		if !ok {
+			i = len(page.Packages)
+			index[t.Package] = i
+			page.Packages = append(page.Packages, htmlPackage{Name: t.Package})
+		}
+		page.Packages[i].Tests = append(page.Packages[i].Tests, t)
+	}
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(h.path, buf.Bytes(), 0644)
+}
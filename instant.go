@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// instantFailures returns the tests that failed in no time
+// without printing anything. That is more often a problem with
+// the environment, such as a setup step in TestMain, than with
+// the test itself. Tests that failed because of a subtest are
+// left out.
+func (r *ResultSummary) instantFailures() []testResult {
+	var instant []testResult
+	for _, t := range r.tests {
+		if t.Status != "FAIL" || t.Elapsed != 0 || hasDetail(t.Output) || r.failedSubtest(t) {
+			continue
+		}
+		instant = append(instant, t)
+	}
+	return instant
+}
+
+// hasDetail reports whether output has lines other than the
+// headers and results of tests.
+func hasDetail(output []string) bool {
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && testName(trimmed) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// failedSubtest reports whether a subtest of t failed.
+func (r *ResultSummary) failedSubtest(t testResult) bool {
+	for _, sub := range r.tests {
+		if sub.Status == "FAIL" && sub.Package == t.Package && strings.HasPrefix(sub.Name, t.Name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// printInstantFailures notes the tests that failed in no time
+// without printing anything.
+func (r *ResultSummary) printInstantFailures() {
+	instant := r.instantFailures()
+	if len(instant) == 0 {
+		return
+	}
+	names := make([]string, len(instant))
+	for i, t := range instant {
+		names[i] = trimPackage(qualifiedName(t))
+	}
+	color.Yellow("Instant failures: %s (failed in no time without output, often a setup problem)", strings.Join(names, ", "))
+}
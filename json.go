@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// event is a single test2json event as printed by go test -json.
+type event struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// parseEvent decodes a line of go test -json output and renders
+// it like the equivalent plain go test output. Lines that are not
+// JSON, such as build errors written to stderr by Go before 1.24,
+// are parsed as is.
+func parseEvent(line string, summary *ResultSummary) {
+	var e event
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil {
+		parse(line, summary)
+		return
+	}
+	switch e.Action {
+	case "output", "build-output", "build-fail":
+		// Since Go 1.24, build errors are reported as events
+		// too, and build-fail events come without output.
+		if e.Output == "" {
+			return
+		}
+	default:
+		return
+	}
+	summary.current = e.Test
+	summary.pkg = e.Package
+	summary.eventTime = e.Time
+	parse(strings.TrimSuffix(e.Output, "\n"), summary)
+}
+
+// hasJSONFlag reports whether go test was asked to print JSON.
+func hasJSONFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "-args" || arg == "--args" {
+			return false
+		}
+		if arg == "-json" || arg == "--json" || arg == "-json=true" || arg == "--json=true" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// junitReport writes a JUnit XML report to a file,
+// with a test suite per package.
+type junitReport struct {
+	path string
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",cdata"`
+}
+
+func (j junitReport) report(summary *ResultSummary) error {
+	var suites junitSuites
+	index := make(map[string]int)
+	elapsed := make(map[string]time.Duration)
+	for _, t := range summary.tests {
+		i, ok := index[t.Package]
+		if !ok {
+			i = len(suites.Suites)
+			index[t.Package] = i
+			suites.Suites = append(suites.Suites, junitSuite{Name: t.Package})
+		}
+		s := &suites.Suites[i]
+		c := junitCase{
+			Classname: t.Package,
+			Name:      t.Name,
+			Time:      junitTime(t.Elapsed),
+		}
+		switch t.Status {
+		case "FAIL":
+			s.Failures++
+			c.Failure = &junitFailure{
+				Message:  "Failed",
+				Contents: strings.Join(t.Output, "\n"),
+			}
+		case "SKIP":
+			s.Skipped++
+			c.Skipped = &struct{}{}
+		}
+		// Subtests run within their parent.
+		if !strings.Contains(t.Name, "/") {
+			elapsed[t.Package] += t.Elapsed
+		}
+		s.Tests++
+		s.Cases = append(s.Cases, c)
+	}
+	for i := range suites.Suites {
+		suites.Suites[i].Time = junitTime(elapsed[suites.Suites[i].Name])
+	}
+
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, append([]byte(xml.Header), append(b, '\n')...), 0644)
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
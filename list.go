@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// listMode is set when go test is run with -list, which
+// prints the names of tests instead of running them.
+var listMode bool
+
+// hasListFlag reports whether args, the arguments for go test,
+// ask it to list tests. Those after -args go to the test binary.
+func hasListFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "-args" || arg == "--args" {
+			return false
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		if name == "list" || name == "test.list" {
+			return true
+		}
+	}
+	return false
+}
+
+// listedName matches the names of tests, benchmarks, examples
+// and fuzz tests printed by go test -list.
+var listedName = regexp.MustCompile(`^(Test|Benchmark|Example|Fuzz)\w*$`)
+
+// parseList parses a line of go test -list output. As no
+// tests ran, their names are only counted and shown in the
+// neutral color, and no results are counted.
+func parseList(line string, summary *ResultSummary) {
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+	var c color.Attribute
+	switch status, pkg, ok := packageResult(trimmed); {
+	case listedName.MatchString(trimmed):
+		summary.listed++
+		c = neutral
+	case ok:
+		summary.seePackage(pkg)
+		switch status {
+		case "ok":
+			c = pass
+		case "FAIL":
+			c = fail
+		}
+	case trimmed == "FAIL":
+		c = fail
+	}
+	if *countsOnly {
+		return
+	}
+	if packagePrefix != "" {
+		line = trimPackageLine(line, trimmed)
+		trimmed = strings.TrimSpace(line)
+	}
+	summary.emit(line, trimmed, c)
+}
+
+// printListed prints the summary of go test -list.
+func (r *ResultSummary) printListed() {
+	color.Cyan("Summary:")
+	color.White("Listed %d tests", r.listed)
+	if len(r.packages) > 0 {
+		color.White("Packages: %d", len(r.packages))
+	}
+}
@@ -8,15 +8,21 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -26,27 +32,167 @@ var (
 	skip = color.FgYellow
 	fail = color.FgHiRed
 
+	// neutral is used for informational lines from the go command.
+	neutral = color.FgHiBlack
+
 	skipnotest bool
 )
 
 const (
 	paletteEnv     = "GOTEST_PALETTE"
 	skipNoTestsEnv = "GOTEST_SKIPNOTESTS"
+	configEnv      = "GOTEST_CONFIG"
+	envFileEnv     = "GOTEST_ENV"
+	stepSummaryEnv = "GITHUB_STEP_SUMMARY"
 )
 
-type ResultSummary struct {
-	pass    int
-	fail    int
-	skipped int
+// show reports whether the summary should be printed
+// at the end of the run.
+func (r *ResultSummary) show() bool {
+	switch {
+	case *noSummary:
+		return false
+	case *summaryOnFail:
+		// Build and go command errors fail the run too.
+		c := r.snapshot()
+		return c.fail > 0 || r.goErrors > 0 || c.skipped > 0
+	}
+	return true
+}
+
+// exitCode returns the exit code of the run given the exit
+// code of go test. Failures are tolerated in up to
+// -max-failed-packages packages and in known flaky tests,
+// while data races and output matching -fail-on-output fail
+// the run even if every test passed, as do packages without
+// tests matching -run with -strict, and running no tests at all
+// with -require-tests.
+func (r *ResultSummary) exitCode(code int) int {
+	// go test exits with 1 if tests failed, but also if the go
+	// command failed before any package was tested, which
+	// -max-failed-packages doesn't tolerate.
+	tested := r.goErrors == 0 && len(r.pkgStatuses) > 0
+	if code == 1 && tested && *maxFailedPackages >= 0 && len(r.failedPackages) <= *maxFailedPackages {
+		code = 0
+	}
+	// Like the summary, only count failures other than those of
+	// known flaky tests.
+	if code == 1 && r.goErrors == 0 && r.snapshot().fail == 0 && r.flaky > 0 {
+		code = 0
+	}
+	if code == 0 && r.races > 0 {
+		return 1
+	}
+	if code == 0 && *strict && r.noMatch > 0 {
+		return 1
+	}
+	if code == 0 && r.forbidden > 0 {
+		return 1
+	}
+	// A run in which every test was skipped still ran tests, while
+	// the bare PASS of go test -v says nothing about it.
+	if code == 0 && *requireTests && r.ranPackages == 0 && !r.onlyNoTestFiles() {
+		fmt.Fprintln(os.Stderr, "gotest: no tests ran")
+		return 1
+	}
+	return code
+}
+
+// onlyNoTestFiles reports whether every package tested had
+// no test files, which -allow-no-test-files doesn't hold
+// against the run.
+func (r *ResultSummary) onlyNoTestFiles() bool {
+	return *allowNoTestFiles && r.noTestFiles > 0 && r.testedPackages == 0
 }
 
 func (r *ResultSummary) Print() {
-	total := r.pass + r.fail + r.skipped
+	if listMode {
+		r.printListed()
+		return
+	}
+	c := r.snapshot()
+	if *banner {
+		r.printVerdict()
+	}
 	color.Cyan("Summary:")
-	color.White("Total: %d", total)
-	color.Green("PASS: %d", r.pass)
-	color.Yellow("SKIP: %d", r.skipped)
-	color.Red("FAIL: %d", r.fail)
+	color.White("Total: %d", c.total())
+	if len(r.packages) > 0 {
+		color.White("Packages: %d", len(r.packages))
+	}
+	if cached, total, percent := r.cacheHitRate(); cached > 0 {
+		color.White("Cache: %d/%d packages cached (%d%%)", cached, total, percent)
+	}
+	if *accessible {
+		color.Green("[PASS] %d passed", c.pass)
+		color.Yellow("[SKIP] %d skipped", c.skipped)
+		color.Red("[FAIL] %d failed", c.fail)
+	} else {
+		color.Green("PASS: %d", c.pass)
+		color.Yellow("SKIP: %d", c.skipped)
+		color.Red("FAIL: %d", c.fail)
+	}
+	if r.flaky > 0 {
+		color.Yellow("Flaky: %d", r.flaky)
+	}
+	if r.goErrors > 0 {
+		color.Red("Build/dependency errors: %d", r.goErrors)
+	}
+	if r.races > 0 {
+		color.Red("Races: %d", r.races)
+	}
+	if r.noMatch > 0 {
+		color.Yellow("No tests to run: %d", r.noMatch)
+	}
+	if r.forbidden > 0 {
+		color.Red("Forbidden output: %d lines", r.forbidden)
+	}
+	if r.stoppedBy != "" {
+		color.Red("Stopped after %s failed", trimPackage(r.stoppedBy))
+	}
+	r.printInstantFailures()
+	if r.parallel > 0 {
+		color.White("Parallelism: %d packages", r.parallel)
+	}
+	if r.slowest != "" {
+		color.White("Slowest test: %s (%.2fs)", r.slowest, r.slowestElapsed.Seconds())
+	}
+
+	if *histogram {
+		r.printHistogram()
+	}
+	if *elapsedByStatus {
+		r.printElapsedByStatus()
+	}
+	if *measureCompile {
+		color.White("Build/overhead: ~%.1fs", r.overhead().Seconds())
+	}
+	if *perTestTimeout > 0 {
+		r.printOvertime()
+	}
+	if *topOutput > 0 {
+		r.printNoisiest(*topOutput)
+	}
+	if *skipReasons {
+		r.printSkipReasons()
+	}
+	if *detail {
+		r.printDetail()
+	}
+	if *failContextLines > 0 {
+		r.printFailContext()
+	}
+	if *compareRuns {
+		r.printComparison()
+	}
+	if *noCacheDisplay {
+		r.printCached()
+	}
+	if *grid {
+		r.printGrid()
+	}
+	if *printFailedCommands {
+		r.printRerunCommands()
+	}
 }
 
 func main() {
@@ -54,64 +200,374 @@ func main() {
 	enableSkipNoTests()
 	enableOnCI()
 
-	os.Exit(gotest(os.Args[1:]))
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// Arguments on the command line override the config file.
+	args, err := parseFlags(append(config, os.Args[1:]...))
+	if err != nil {
+		os.Exit(2)
+	}
+	applyClean()
+	listMode = hasListFlag(args)
+	if err := loadPusher(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	loadEvents()
+	if err := loadRunID(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadFlakyList(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadFailOnOutput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadTrimPrefix(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadPreambleEnd(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadWebhookTemplate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadTemplate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := enableColorFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *hangAction != "warn" && *hangAction != "quit" {
+		fmt.Fprintf(os.Stderr, "invalid -hang-action %q: must be warn or quit\n", *hangAction)
+		os.Exit(2)
+	}
+	if *wrapLines {
+		wrapWidth = terminalWidth()
+	}
+	// Rules may name the colors set by the flags.
+	if err := loadRules(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *outputDir != "" {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	printRunID()
+	if *jsonlInput {
+		os.Exit(replay(color.Output, os.Stdin))
+	}
+	if *dryParse != "" {
+		f, err := os.Open(*dryParse)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(replay(color.Output, f))
+	}
+	if *aggregate {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "gotest: -aggregate needs the logs to combine")
+			os.Exit(2)
+		}
+		var logs []io.Reader
+		for _, name := range args {
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			logs = append(logs, f)
+		}
+		os.Exit(replay(color.Output, logs...))
+	}
+	if *since != "" {
+		files, err := gitChangedFiles(*since)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		pkgs := changedPackages(files)
+		if len(pkgs) == 0 {
+			fmt.Printf("No packages changed since %s\n", *since)
+			os.Exit(0)
+		}
+		args = withPackages(args, pkgs)
+	}
+	os.Exit(gotest(args))
 }
 
+// replay renders go test output read from rs, plain or -json,
+// to w instead of running go test. The output of every reader
+// adds up to a single summary.
+func replay(w io.Writer, rs ...io.Reader) int {
+	color.Output = w
+	summary := &ResultSummary{}
+	start := time.Now()
+	for _, r := range rs {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		consume(&wg, r, summary, parseEvent)
+	}
+	summary.elapsed = time.Since(start)
+	summary.finish()
+	code := 0
+	if summary.snapshot().fail > 0 || summary.goErrors > 0 {
+		code = 1
+	}
+	code = summary.exitCode(code)
+	summary.publish()
+	return runFooter(code)
+}
+
+// gotest runs go test with args, once or with -repeat-until-fail
+// until it fails, between the -before and -after hooks, and
+// returns the exit code of the run. Only the last run is
+// published in the reports.
 func gotest(args []string) int {
+	if *envBanner {
+		printBanner(args)
+	}
+	if *before != "" {
+		if err := runSetup(*before); err != nil {
+			fmt.Fprintf(os.Stderr, "setup command %q failed: %v\n", *before, err)
+			return 1
+		}
+	}
+	if *after != "" {
+		// Tear down whatever the tests did, even if they failed
+		// or were interrupted, or gotest itself was.
+		var once sync.Once
+		teardown := func() {
+			once.Do(func() {
+				if err := runHook(*after); err != nil {
+					fmt.Fprintf(os.Stderr, "teardown command %q failed: %v\n", *after, err)
+				}
+			})
+		}
+		defer teardownOnSignal(teardown)()
+		defer teardown()
+	}
+	var summary *ResultSummary
+	var code int
+	if *repeatUntilFail {
+		summary, code = repeatUntilFailure(args, *repeatMax)
+	} else {
+		summary, code = test(args)
+	}
+	if isBrokenPipe(summary.writeErr) {
+		return code
+	}
+	if *envDiffFlag {
+		printEnvDiff(childEnv())
+	}
+	summary.publish()
+	return runFooter(code)
+}
+
+// test runs go test with args once, printing its output and
+// summary, and returns the summary and exit code of the run.
+func test(args []string) (*ResultSummary, int) {
+	summary := &ResultSummary{rerunCached: *rerunCachedFlag}
+	defer watchSnapshots(summary)()
+	if *parallel > 0 {
+		// gotest took every -p, so go test gets exactly one.
+		args = append([]string{"-p=" + strconv.Itoa(*parallel)}, args...)
+		summary.parallel = *parallel
+	}
+	if *nocache {
+		// A -count of the user's own wins.
+		flagArgs, _, _ := splitArgs(args)
+		if _, ok := flagValue(flagArgs, "count"); !ok {
+			args = append([]string{"-count=1"}, args...)
+		}
+	}
+	var f *formatter
+	if *pipe != "" {
+		var err error
+		if f, err = startFormatter(*pipe); err != nil {
+			fmt.Fprintf(os.Stderr, "pipe command %q failed: %v\n", *pipe, err)
+		} else {
+			summary.tee = f
+		}
+	}
+	start := time.Now()
+	var code int
+	switch {
+	case *keepGoing:
+		code = runBatches(args, 0, summary)
+	case *batch > 0:
+		code = runBatches(args, *batch, summary)
+	default:
+		code = run(args, summary)
+	}
+	if *rerunCachedFlag && !isBrokenPipe(summary.writeErr) {
+		if c := rerunCached(args, summary); code == 0 {
+			code = c
+		}
+	}
+	if f != nil {
+		f.wait()
+	}
+	if isBrokenPipe(summary.writeErr) {
+		return summary, 128 + int(sigpipe)
+	}
+	summary.elapsed = time.Since(start)
+	summary.finish()
+	if *verboseOnFail && code != 0 {
+		rerunVerbose(args, summary)
+	}
+	return summary, summary.exitCode(code)
+}
+
+// run runs go test with args, parsing its output into summary,
+// and returns its exit status.
+func run(args []string, summary *ResultSummary) int {
 	var wg sync.WaitGroup
 	wg.Add(1)
-	defer wg.Wait()
 
 	r, w := io.Pipe()
-	defer w.Close()
 
 	args = append([]string{"test"}, args...)
 	cmd := exec.Command("go", args...)
-	cmd.Stderr = w
-	cmd.Stdout = w
-	cmd.Env = os.Environ()
+	var out io.Writer = w
+	if summary.tee != nil {
+		out = io.MultiWriter(w, summary.tee)
+	}
+	cmd.Stderr = out
+	cmd.Stdout = out
+	flush := func() {}
+	if *tagStderr || *separateStreams {
+		flush = splitStreams(cmd, w, summary.tee)
+	}
+	cmd.Env = childEnv()
+	signalChild := func(sig os.Signal) { cmd.Process.Signal(sig) }
+	// The go command leaves the test binaries to be signaled
+	// through the process group, as a terminal does. Under a
+	// supervisor doing so itself, go test stays in ours, unless
+	// gotest has to stop the test binaries itself.
+	ownGroup := !*noSignalForward || *stopOnPackageFail
+	if ownGroup {
+		signalChild = ownProcessGroup(cmd)
+	}
 
+	if runOnStart(cmd.Args) != nil {
+		return 1
+	}
 	if err := cmd.Start(); err != nil {
 		log.Print(err)
-		wg.Done()
 		return 1
 	}
 
-	go consume(&wg, r)
+	parseLine := parse
+	if hasJSONFlag(args) {
+		parseLine = parseEvent
+	}
+	summary.signalChild = signalChild
+	var in io.Reader = r
+	if *hangTimeout > 0 {
+		// SIGQUIT is for the tests, which dump their goroutines
+		// through go test.
+		dog := startWatchdog(*hangTimeout, testsSignaler(cmd))
+		defer dog.stop()
+		in = watchedReader{r, dog}
+	}
+	go consume(&wg, in, summary, parseLine)
+
+	// In a process group of its own, go test only gets the
+	// signals gotest relays.
+	if ownGroup {
+		defer forwardSignals(signalChild)()
+	}
+
+	// Wait returns once go test has exited and all of its output
+	// has been written to the pipe. Only then is it safe to close
+	// the pipe, so that consume reads every line before it sees
+	// EOF and the summary is complete once consume is done.
+	err := cmd.Wait()
+	flush()
+	w.Close()
+	wg.Wait()
+	summary.signalChild = nil
+	return exitStatus(cmd, err)
+}
 
-	sigc := make(chan os.Signal)
+// forwardSignals relays the signals gotest receives with relay
+// until the returned function is called.
+func forwardSignals(relay func(os.Signal)) func() {
+	sigc := make(chan os.Signal, 1)
 	done := make(chan struct{})
-	defer func() {
-		done <- struct{}{}
-	}()
 	signal.Notify(sigc)
+	atomic.AddInt32(&relaying, 1)
 
 	go func() {
 		for {
 			select {
 			case sig := <-sigc:
-				cmd.Process.Signal(sig)
+				if sig == sigpipe {
+					// Our output is gone, which consume
+					// handles. It says nothing to go test.
+					continue
+				}
+				if isSnapshotSignal(sig) {
+					// It's for gotest, which prints the
+					// counts so far.
+					continue
+				}
+				relay(sig)
 			case <-done:
 				return
 			}
 		}
 	}()
+	return func() {
+		atomic.AddInt32(&relaying, -1)
+		signal.Stop(sigc)
+		done <- struct{}{}
+	}
+}
 
-	if err := cmd.Wait(); err != nil {
-		if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
-			return ws.ExitStatus()
+// exitStatus returns the exit status of cmd given the
+// error returned by its Wait method.
+func exitStatus(cmd *exec.Cmd, err error) int {
+	if err == nil {
+		return 0
+	}
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		if ws.Signaled() {
+			// Follow the shell convention for processes
+			// killed by a signal.
+			fmt.Fprintf(os.Stderr, "go test terminated by signal: %v\n", ws.Signal())
+			return 128 + int(ws.Signal())
 		}
-		return 1
+		return ws.ExitStatus()
 	}
-	return 0
+	return 1
 }
 
-func consume(wg *sync.WaitGroup, r io.Reader) {
+func consume(wg *sync.WaitGroup, r io.Reader, summary *ResultSummary, parseLine func(string, *ResultSummary)) {
 	defer wg.Done()
 	reader := bufio.NewReader(r)
-	summary := &ResultSummary{}
+	t := stdoutTitle()
+	defer t.restore()
 	for {
-		l, _, err := reader.ReadLine()
+		start := time.Now()
+		l, err := readLine(reader)
+		summary.profile.read += time.Since(start)
 		if err == io.EOF {
 			break
 		}
@@ -119,48 +575,452 @@ func consume(wg *sync.WaitGroup, r io.Reader) {
 			log.Print(err)
 			break
 		}
-		parse(string(l), summary)
+		start = time.Now()
+		summary.stderr = strings.HasPrefix(l, stderrMark)
+		l = strings.TrimPrefix(l, stderrMark)
+		if outputPreamble.skip(l) {
+			continue
+		}
+		summary.mu.Lock()
+		parseLine(l, summary)
+		summary.mu.Unlock()
+		summary.profile.parse += time.Since(start)
+		summary.profile.lines++
+		t.update(summary)
+		if isBrokenPipe(summary.writeErr) {
+			// Nobody reads our output anymore. Stop go test,
+			// test binaries and all, and drain its output so
+			// it isn't blocked writing.
+			if summary.signalChild != nil {
+				summary.signalChild(os.Interrupt)
+			}
+			io.Copy(ioutil.Discard, reader)
+			break
+		}
+	}
+}
+
+// finish completes the run: it prints the output held back
+// and the summary.
+func (r *ResultSummary) finish() {
+	if *skipReasons {
+		r.skipReason("")
+	}
+	r.flush()
+	r.endPackage(r.pkg)
+	if *compareRuns {
+		r.compareWithPrevious()
+	}
+	if r.writeErr == nil && r.show() {
+		r.Print()
+	}
+	if *selfProfile {
+		r.printProfile()
+	}
+}
+
+// flush prints the output held back, unless it can't be
+// written anymore.
+func (r *ResultSummary) flush() {
+	if r.writeErr != nil {
+		return
+	}
+	r.endStack()
+	r.printTruncated("")
+	r.releaseStderr()
+	r.releaseFocus()
+	r.printTrees("")
+	r.releaseHeld()
+	r.printSorted()
+}
+
+// publish sends the results of the finished run to the metrics,
+// webhook, collector and events socket, and writes the reports.
+func (r *ResultSummary) publish() {
+	pushMetrics(r)
+	postWebhook(r)
+	exportSpans(r)
+	if events != nil {
+		events.send(summaryEvent{"summary", newJSONSummary(r)})
+		events.close()
+	}
+
+	for _, rep := range reporters() {
+		if err := rep.report(r); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// readLine reads a whole line, however long, without its line ending.
+func readLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		l, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, l...)
+		if !isPrefix {
+			return string(line), nil
+		}
 	}
-	summary.Print()
 }
 
 func parse(line string, summary *ResultSummary) {
+	if listMode {
+		parseList(line, summary)
+		return
+	}
+	// Output from Windows may end in "\r\n", which ReadLine doesn't
+	// remove from the Output of -json events.
+	line = strings.TrimSuffix(line, "\r")
 	trimmed := strings.TrimSpace(line)
-	if strings.HasPrefix(trimmed, "=== RUN") {
+	if *skipReasons {
+		summary.skipReason(trimmed)
+	}
+	summary.record(line, trimmed)
+	summary.timeTest(trimmed)
+	summary.trackPackage(trimmed)
+	isRun := strings.HasPrefix(trimmed, "=== RUN")
+	if isRun && *tree {
+		// Keep the order in which subtests start.
+		summary.group(line, trimmed, 0)
+	}
+	// Test starts are never shown, and the markers of parallel
+	// tests pausing and continuing only with
+	// -show-parallel-markers.
+	if isRun || strings.HasPrefix(trimmed, "=== ") && !*showParallelMarkers {
 		return
 	}
-	defer color.Unset()
+
+	// Results of subtests are already reflected in the
+	// result of their parent, which fails if any of them do.
+	n := 1
+	if *mergeSubtests && strings.Contains(testName(trimmed), "/") {
+		n = 0
+	}
+
+	forbidden := isForbidden(line, trimmed)
+	if forbidden {
+		summary.forbidden++
+	}
 
 	var c color.Attribute
 	switch {
+	// Packages without test files ran no tests, so they are
+	// never counted, and with GOTEST_SKIPNOTESTS not shown.
 	case strings.Contains(trimmed, "[no test files]"):
 		if skipnotest {
 			return
 		}
 
+	// === PAUSE, === CONT and === NAME
+	case strings.HasPrefix(trimmed, "=== "):
+		c = neutral
+
+	// data race
+	case trimmed == "WARNING: DATA RACE":
+		summary.races++
+		c = fail
+
+	// -run matched no tests
+	case trimmed == "testing: warning: no tests to run":
+		summary.pkgNoMatch = true
+		c = skip
+	case strings.HasSuffix(trimmed, "[no tests to run]"):
+		c = skip
+
+	// go command warnings
+	case strings.HasPrefix(trimmed, "go: warning:"):
+		if *hideGoWarnings {
+			return
+		}
+		c = skip
+
+	// module resolution
+	case isModuleNoise(trimmed):
+		if *hideModuleNoise {
+			return
+		}
+		c = neutral
+
+	// go command errors, such as failing to download a module
+	case isGoError(trimmed):
+		summary.goErrors++
+		c = fail
+
+	// cached results that are tested again
+	case summary.rerunCached && isCached(trimmed):
+		c = pass
+
 	case strings.HasPrefix(trimmed, "--- PASS"): // passed
 		fallthrough
 	case strings.HasPrefix(trimmed, "ok"):
 		fallthrough
 	case strings.HasPrefix(trimmed, "PASS"):
-		summary.pass += 1
+		summary.pass += n
 		c = pass
+		// A bare PASS only repeats the ok line that follows.
+		if trimmed == "PASS" && *hidePassMarker {
+			return
+		}
 
 	// skipped
 	case strings.HasPrefix(trimmed, "--- SKIP"):
-		summary.skipped += 1
+		summary.skipped += n
+		c = skip
+
+	// known flaky
+	case strings.HasPrefix(trimmed, "--- FAIL") && isFlaky(testName(trimmed)):
+		summary.flaky += n
+		summary.pkgFlaky++
+		summary.excuseParents(testName(trimmed))
+		c = skip
+
+	case strings.HasPrefix(trimmed, "FAIL") && summary.failedOnlyByFlaky(trimmed):
 		c = skip
 
 	// failed
 	case strings.HasPrefix(trimmed, "--- FAIL"):
+		summary.pkgFailures++
+		summary.failTest(testName(trimmed), n)
 		fallthrough
 	case strings.HasPrefix(trimmed, "FAIL"):
-		summary.fail += 1
+		summary.fail += n
 		c = fail
 	}
 
-	color.Set(c)
-	fmt.Printf("%s\n", line)
+	if _, _, ok := packageResult(trimmed); ok && summary.rerunCached {
+		summary.saveCheckpoint()
+	}
+
+	if ec, ok := summary.exampleDiff(trimmed); ok {
+		c = ec
+	}
+	if forbidden {
+		c = fail
+	}
+	// Rules can't change the counts, only how lines look.
+	if r, ok := matchRule(trimmed); ok {
+		if r.hide {
+			return
+		}
+		c = r.c
+	}
+	if summary.collapseStack(line, trimmed) {
+		return
+	}
+	if isFastPass(trimmed) {
+		// Counted, just not shown.
+		if *failuresLast {
+			summary.release(summary.current)
+		}
+		return
+	}
+	if summary.truncate(trimmed) {
+		return
+	}
+	if summary.isRepeatedBlank(trimmed) {
+		return
+	}
+	if *skipReasons && summary.holdLog(line, trimmed, c) {
+		return
+	}
+	if *countsOnly {
+		return
+	}
+	if out, ok := renderResult(line, trimmed, summary.pkg); ok {
+		line = out
+	}
+	if packagePrefix != "" {
+		line = trimPackageLine(line, trimmed)
+		trimmed = strings.TrimSpace(line)
+	}
+	if len(pathMapping) > 0 {
+		line = mapPaths(line)
+	}
+	switch {
+	case *accessible:
+		line, c = labelLine(line, trimmed), 0
+	case *colorByPackage:
+		line, c = colorPackageHue(line, trimmed, c)
+	case *style == "minimal" && c == pass:
+		line, c = colorPackage(line, trimmed), 0
+	}
+	if *redact {
+		line = redactDurations(line)
+	}
+	if summary.stderr {
+		line = stderrTag + line
+	}
+	summary.emit(line, trimmed, c)
+}
+
+// emit prints a parsed line in color c, grouping or holding
+// it back first as the flags ask.
+func (r *ResultSummary) emit(line, trimmed string, c color.Attribute) {
+	if r.holdStderr(line, c) {
+		return
+	}
+	if _, _, ok := packageResult(trimmed); ok && *separateStreams {
+		defer r.releaseStderr()
+	}
+	if *sortOutput {
+		r.buffer(line, trimmed, c)
+		return
+	}
+	// A bare FAIL after a package is the overall result of
+	// the run, not the start of another package.
+	if r.separate && trimmed != "FAIL" {
+		r.println(separator, neutral)
+		r.separate = false
+	}
+	if _, _, ok := packageResult(trimmed); ok && *separators {
+		r.separate = true
+	}
+	if *focusFailures && r.focus(line, trimmed, c) {
+		return
+	}
+	r.display(line, trimmed, c)
+}
+
+// display prints line in color c, unless -tree or
+// -failures-last hold it back.
+func (r *ResultSummary) display(line, trimmed string, c color.Attribute) {
+	if *tree && r.group(line, trimmed, c) {
+		return
+	}
+	if *failuresLast && r.hold(line, trimmed, c) {
+		return
+	}
+	r.println(line, c)
+}
+
+// separator is printed between packages with -separators.
+var separator = strings.Repeat("-", 40)
+
+// println prints line in color c. Like the summary, it is
+// written to color.Output.
+func (r *ResultSummary) println(line string, c color.Attribute) {
+	if *colorScope == "marker" && c != 0 {
+		line, c = colorMarker(line, c)
+	}
+	lines := []string{line}
+	if wrapWidth > 0 {
+		lines = wrap(line, wrapWidth)
+	}
+	for _, l := range lines {
+		color.Set(c)
+		_, err := fmt.Fprintf(color.Output, "%s\n", l)
+		color.Unset()
+		if err != nil {
+			r.writeErr = err
+			return
+		}
+	}
+}
+
+// colorPackage colors only the import path of an "ok" package
+// line, leaving any other line uncolored.
+func colorPackage(line, trimmed string) string {
+	status, pkg, ok := packageResult(trimmed)
+	if !ok || status != "ok" {
+		return line
+	}
+	return strings.Replace(line, pkg, color.New(pass).Sprint(pkg), 1)
+}
+
+// packageHues are the colors of packages with -color-by-package,
+// leaving out those of results.
+var packageHues = []color.Attribute{
+	color.FgBlue, color.FgMagenta, color.FgCyan, color.FgYellow,
+	color.FgHiBlue, color.FgHiMagenta, color.FgHiCyan, color.FgHiYellow,
+}
+
+// packageHue returns the color of pkg, picked by hashing its
+// path so that a package has the same color in every run.
+func packageHue(pkg string) color.Attribute {
+	h := fnv.New32a()
+	h.Write([]byte(pkg))
+	return packageHues[h.Sum32()%uint32(len(packageHues))]
+}
+
+// colorPackageHue colors the import path of a package result
+// line in its packageHue and the status marker in c. Other
+// lines stay colored as a whole.
+func colorPackageHue(line, trimmed string, c color.Attribute) (string, color.Attribute) {
+	_, pkg, ok := packageResult(trimmed)
+	if !ok {
+		return line, c
+	}
+	if c != 0 {
+		line, c = colorMarker(line, c)
+	}
+	return strings.Replace(line, pkg, color.New(packageHue(pkg)).Sprint(pkg), 1), c
+}
+
+// marker returns the status a trimmed result line starts with,
+// such as "--- FAIL:" or "ok", or "" if it isn't a result line.
+func marker(trimmed string) string {
+	for _, h := range resultHeaders {
+		if strings.HasPrefix(trimmed, h) {
+			return h
+		}
+	}
+	if f := strings.Fields(trimmed); len(f) > 0 {
+		switch f[0] {
+		case "ok", "FAIL", "PASS", "?":
+			return f[0]
+		}
+	}
+	return ""
+}
+
+// colorMarker colors only the status marker of a result line
+// for -color-scope marker. Other lines stay colored as a whole.
+func colorMarker(line string, c color.Attribute) (string, color.Attribute) {
+	m := marker(strings.TrimSpace(line))
+	if m == "" {
+		return line, c
+	}
+	return strings.Replace(line, m, color.New(c).Sprint(m), 1), 0
+}
+
+// isBrokenPipe reports whether err is the result of writing
+// to a pipe with no readers, such as when piping into head.
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// moduleVerbs are the verbs the go command uses to report
+// progress resolving modules, as in "go: downloading ...".
+var moduleVerbs = []string{
+	"downloading", "extracting", "finding", "found",
+	"added", "upgraded", "downgraded", "removed",
+}
+
+// isModuleNoise reports whether a trimmed line is an informational
+// module resolution message rather than an error.
+func isModuleNoise(trimmed string) bool {
+	if !strings.HasPrefix(trimmed, "go: ") {
+		return false
+	}
+	rest := strings.TrimPrefix(trimmed, "go: ")
+	for _, v := range moduleVerbs {
+		if strings.HasPrefix(rest, v+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoError reports whether a trimmed line is an error from the
+// go command, such as failing to download a module, which keeps
+// the tests from building.
+func isGoError(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "go: ") && !isModuleNoise(trimmed) &&
+		!strings.HasPrefix(trimmed, "go: warning:")
 }
 
 func enableOnCI() {
@@ -196,6 +1056,46 @@ func enablePalette() {
 	}
 }
 
+// enableColorFlags applies the color flags. -color takes precedence
+// over terminal and CI detection, and -pass-color, -fail-color and
+// -skip-color take precedence over GOTEST_PALETTE.
+func enableColorFlags() error {
+	switch *colorMode {
+	case "auto":
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("invalid -color %q: must be always, auto or never", *colorMode)
+	}
+	if *style != "full" && *style != "minimal" {
+		return fmt.Errorf("invalid -style %q: must be full or minimal", *style)
+	}
+	if *colorScope != "line" && *colorScope != "marker" {
+		return fmt.Errorf("invalid -color-scope %q: must be line or marker", *colorScope)
+	}
+
+	for _, f := range []struct {
+		name string
+		attr *color.Attribute
+	}{
+		{*passColor, &pass},
+		{*failColor, &fail},
+		{*skipColor, &skip},
+	} {
+		if f.name == "" {
+			continue
+		}
+		c, ok := colors[f.name]
+		if !ok {
+			return fmt.Errorf("unknown color %q", f.name)
+		}
+		*f.attr = c
+	}
+	return nil
+}
+
 func enableSkipNoTests() {
 	v := os.Getenv(skipNoTestsEnv)
 	if v == "" {
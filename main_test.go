@@ -0,0 +1,998 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var update = flag.Bool("update", false, "update the golden output in testdata")
+
+// runMainEnv makes the test binary run gotest itself, so that
+// tests can run gotest as a child process, flags and all.
+const runMainEnv = "GOTEST_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// gotestCmd returns a command running gotest with args, with
+// an empty config and none of the user's settings.
+func gotestCmd(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GOTEST_") || strings.HasPrefix(kv, stepSummaryEnv+"=") {
+			continue
+		}
+		cmd.Env = append(cmd.Env, kv)
+	}
+	cmd.Env = append(cmd.Env, runMainEnv+"=1", configEnv+"="+os.DevNull)
+	return cmd
+}
+
+// stubGo returns a command running gotest with args, with a go
+// command that runs script in the shell instead of testing.
+// The returned function removes the stub.
+func stubGo(t *testing.T, script string, args ...string) (*exec.Cmd, func()) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub go command is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cmd := gotestCmd(args...)
+	cmd.Env = append(cmd.Env, "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return cmd, func() { os.RemoveAll(dir) }
+}
+
+// exitCodeOf returns the exit code of a command given the
+// error running it returned.
+func exitCodeOf(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	if ee, ok := err.(*exec.ExitError); ok {
+		return ee.ExitCode()
+	}
+	t.Fatal(err)
+	return 0
+}
+
+// goldenTests are the go test output saved in testdata, the
+// arguments to render it with and the exit code expected. The
+// output is compared to that in the golden file, which is the
+// fixture followed by .golden unless named otherwise.
+var goldenTests = []struct {
+	fixture string
+	golden  string
+	args    []string
+	code    int
+}{
+	{fixture: "fail.txt", code: 1},
+	{fixture: "fail.jsonl", code: 1},
+	{fixture: "fail.txt", golden: "failures-last.golden", args: []string{"-failures-last"}, code: 1},
+	{fixture: "fail.txt", golden: "elapsed-by-status.golden", args: []string{"-elapsed-by-status"}, code: 1},
+	{fixture: "fail.txt", golden: "counts-only.golden", args: []string{"-counts-only"}, code: 1},
+	{fixture: "fail.jsonl", golden: "counts-only-json.golden", args: []string{"-counts-only"}, code: 1},
+	{fixture: "fail.jsonl", golden: "template.golden", args: []string{"-template", ">>> {{.Status}} {{.Package}}.{{.Test}} ({{.Elapsed}})"}, code: 1},
+	{fixture: "crlf.txt", code: 1},
+	{fixture: "buildfail.jsonl", code: 1},
+	{fixture: "skip.txt"},
+	{fixture: "skip.txt", golden: "skip-reasons.golden", args: []string{"-skip-reasons"}},
+	{fixture: "skip.txt", golden: "hide-pass-marker.golden", args: []string{"-hide-pass-marker"}},
+	{fixture: "skip.txt", golden: "fail-on-output.golden", args: []string{"-fail-on-output", "docker|TODO"}, code: 1},
+	{fixture: "moderror.txt", code: 1},
+	{fixture: "moderror.txt", golden: "moderror-max-failed.golden", args: []string{"-max-failed-packages", "0"}, code: 1},
+	{fixture: "moderror.txt", golden: "moderror-summary-on-fail.golden", args: []string{"-summary-on-fail"}, code: 1},
+	{fixture: "list.txt", golden: "list-summary-on-fail.golden", args: []string{"-list", ".", "-summary-on-fail"}},
+	{fixture: "download.txt"},
+	{fixture: "download.txt", golden: "download-hidden.golden", args: []string{"-hide-module-noise"}},
+	{fixture: "warning.txt"},
+	{fixture: "warning.txt", golden: "warning-hidden.golden", args: []string{"-hide-go-warnings"}},
+	{fixture: "nomatch.txt"},
+	{fixture: "nomatch.txt", golden: "nomatch-strict.golden", args: []string{"-strict"}, code: 1},
+	{fixture: "example.txt", code: 1},
+	{fixture: "instant.txt", code: 1},
+	{fixture: "panic.txt", code: 1},
+	{fixture: "race.txt", code: 1},
+	{fixture: "panic.txt", golden: "panic-collapse-stacks.golden", args: []string{"-collapse-stacks", "3"}, code: 1},
+	{fixture: "list.txt", args: []string{"-list", "."}},
+	{fixture: "cache.txt", code: 1},
+	{fixture: "cache.txt", golden: "separators.golden", args: []string{"-separators"}, code: 1},
+	{fixture: "cache.txt", golden: "accessible.golden", args: []string{"-accessible"}, code: 1},
+	{fixture: "subtests.txt", code: 1},
+	{fixture: "subtests.txt", golden: "merge-subtests.golden", args: []string{"-merge-subtests"}, code: 1},
+	{fixture: "subtests.txt", golden: "tree.golden", args: []string{"-tree"}, code: 1},
+	{fixture: "mixed.txt", args: []string{"-detail"}, code: 1},
+	{fixture: "mixed.txt", golden: "hide-faster-than.golden", args: []string{"-hide-faster-than", "1s"}, code: 1},
+	{fixture: "focus.txt", code: 1},
+	{fixture: "focus.txt", golden: "focus-failures.golden", args: []string{"-focus-failures"}, code: 1},
+	{fixture: "paths.txt", code: 1},
+	{fixture: "paths.txt", golden: "map-path.golden", args: []string{"-map-path", "/app=/home/me/project", "-map-path", "/go/pkg/mod/=/home/me/go/pkg/mod"}, code: 1},
+	{fixture: "noisy.txt"},
+	{fixture: "noisy.txt", golden: "clean.golden", args: []string{"-clean"}},
+	{fixture: "noisy.txt", golden: "clean-override.golden", args: []string{"-clean", "-hide-module-noise=false", "-hide-faster-than", "0"}},
+	{fixture: "context.txt", code: 1},
+	{fixture: "context.txt", golden: "fail-context.golden", args: []string{"-fail-context", "2"}, code: 1},
+	{fixture: "mixed.txt", golden: "print-failed-commands.golden", args: []string{"-print-failed-commands"}, code: 1},
+	{fixture: "durations.txt", args: []string{"-histogram"}},
+	{fixture: "durations.txt", golden: "per-test-timeout.golden", args: []string{"-per-test-timeout", "1s"}},
+	{fixture: "durations.txt", golden: "redact-durations.golden", args: []string{"-redact-durations"}},
+	{fixture: "flaky.txt", args: []string{"-banner", "-flaky-list", filepath.Join("testdata", "flaky.list")}},
+	{fixture: "flaky.txt", golden: "flaky-accessible.golden", args: []string{"-accessible", "-flaky-list", filepath.Join("testdata", "flaky.list")}},
+	{fixture: "flakysub.txt", args: []string{"-flaky-list", filepath.Join("testdata", "flaky.list")}},
+	{fixture: "truncate.txt", args: []string{"-max-lines-per-test", "3"}, code: 1},
+	{fixture: "truncate.txt", golden: "top-output.golden", args: []string{"-top-output", "2"}, code: 1},
+}
+
+// TestGolden renders the output saved in testdata and compares
+// it to the golden output. Run with -update to update the latter
+// after checking the differences.
+func TestGolden(t *testing.T) {
+	for _, tt := range goldenTests {
+		golden := tt.golden
+		if golden == "" {
+			golden = tt.fixture + ".golden"
+		}
+		t.Run(golden, func(t *testing.T) {
+			args := append([]string{"-color", "never", "-dry-parse", filepath.Join("testdata", tt.fixture)}, tt.args...)
+			out, err := gotestCmd(args...).Output()
+			if code := exitCodeOf(t, err); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			path := filepath.Join("testdata", golden)
+			if *update {
+				if err := ioutil.WriteFile(path, out, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, want) {
+				t.Errorf("output differs from %s:\n%s", path, lineDiff(string(want), string(out)))
+			}
+		})
+	}
+}
+
+// TestGoldenFixtures checks that every fixture in testdata is
+// rendered by TestGolden.
+func TestGoldenFixtures(t *testing.T) {
+	tested := make(map[string]bool)
+	for _, tt := range goldenTests {
+		tested[tt.fixture] = true
+	}
+	for _, pattern := range []string{"testdata/*.txt", "testdata/*.jsonl"} {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if !tested[filepath.Base(f)] {
+				t.Errorf("%s is not in goldenTests", f)
+			}
+		}
+	}
+}
+
+// lineDiff returns the lines of want and got that differ,
+// marked with - and +.
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			b.WriteString("-" + wl + "\n+" + gl + "\n")
+		}
+	}
+	return b.String()
+}
+
+// BenchmarkParse parses a large synthetic go test output.
+func BenchmarkParse(b *testing.B) {
+	var in bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "=== RUN   Test%d\n    x_test.go:1: log %d\n--- PASS: Test%d (0.00s)\n", i, i, i)
+	}
+	in.WriteString("PASS\nok  \texample.com/x\t1.000s\n")
+
+	out := color.Output
+	defer func() { color.Output = out }()
+	color.Output = ioutil.Discard
+	b.SetBytes(int64(in.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		consume(&wg, bytes.NewReader(in.Bytes()), &ResultSummary{}, parse)
+	}
+}
+
+func TestExitStatusSignaled(t *testing.T) {
+	cmd, cleanup := stubGo(t, "echo '=== RUN   TestA'\nkill -TERM $$\n", "-color", "never")
+	defer cleanup()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if code, want := exitCodeOf(t, err), 128+15; code != want {
+		t.Errorf("exit code = %d, want %d", code, want)
+	}
+	if !strings.Contains(stderr.String(), "go test terminated by signal: terminated") {
+		t.Errorf("stderr = %q, want the signal reported", stderr.String())
+	}
+}
+
+// epipeWriter fails every write as if its reader went away.
+type epipeWriter struct{}
+
+func (epipeWriter) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+}
+
+func TestConsumeBrokenPipe(t *testing.T) {
+	out := color.Output
+	defer func() { color.Output = out }()
+	color.Output = epipeWriter{}
+
+	var signals []os.Signal
+	summary := &ResultSummary{signalChild: func(sig os.Signal) { signals = append(signals, sig) }}
+	in := strings.NewReader("--- FAIL: TestA (0.00s)\n--- FAIL: TestB (0.00s)\nFAIL\n")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	consume(&wg, in, summary, parse)
+
+	if !isBrokenPipe(summary.writeErr) {
+		t.Errorf("writeErr = %v, want EPIPE", summary.writeErr)
+	}
+	if len(signals) != 1 || signals[0] != os.Interrupt {
+		t.Errorf("signals = %v, want [interrupt]", signals)
+	}
+	if summary.fail != 1 {
+		t.Errorf("fail = %d, want 1 as parsing stops at the first write", summary.fail)
+	}
+	if in.Len() != 0 {
+		t.Errorf("%d bytes left unread, want the output drained", in.Len())
+	}
+}
+
+func TestBrokenPipeStopsTests(t *testing.T) {
+	// The test binaries, here sleep, must stop too, not just go
+	// test, or they keep its output open until they are done.
+	cmd, cleanup := stubGo(t, "echo '--- PASS: TestA (0.00s)'\nsleep 1\necho '--- PASS: TestB (0.00s)'\nsleep 30\n", "-color", "never", "-v")
+	defer cleanup()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(stdout).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if code, want := exitCodeOf(t, err), 128+int(sigpipe); code != want {
+			t.Errorf("exit code = %d, want %d", code, want)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("gotest kept running after its output was closed")
+	}
+}
+
+// waitForStub waits for a stub go command to write its pid to
+// the file pid, and for gotest to be done starting it.
+func waitForStub(t *testing.T, pid string) {
+	t.Helper()
+	for i := 0; ; i++ {
+		if _, err := os.Stat(pid); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the stub go command didn't start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+// killStub kills the stub go command whose pid is in the file
+// pid, in case gotest didn't stop it.
+func killStub(pid string) {
+	if b, err := ioutil.ReadFile(pid); err == nil {
+		if p, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			if proc, err := os.FindProcess(p); err == nil {
+				proc.Kill()
+			}
+		}
+	}
+}
+
+func TestSignalForward(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		relayed bool
+	}{
+		{args: nil, relayed: true},
+		{args: []string{"-no-signal-forward"}, relayed: false},
+	} {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gotest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			relayed, pid := filepath.Join(dir, "relayed"), filepath.Join(dir, "pid")
+			script := "trap 'touch " + relayed + "; exit 0' TERM\n" +
+				"echo $$ > " + pid + "\n" +
+				"echo '=== RUN   TestA'\n" +
+				"while :; do sleep 0.1; done\n"
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never", "-v"}, tt.args...)...)
+			defer cleanup()
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer killStub(pid)
+			go io.Copy(ioutil.Discard, stdout)
+			waitForStub(t, pid)
+			cmd.Process.Signal(syscall.SIGTERM)
+			cmd.Wait()
+
+			time.Sleep(200 * time.Millisecond)
+			_, err = os.Stat(relayed)
+			if got := err == nil; got != tt.relayed {
+				t.Errorf("signal relayed = %v, want %v", got, tt.relayed)
+			}
+		})
+	}
+}
+
+func TestFlakyExitCode(t *testing.T) {
+	flaky, err := ioutil.ReadFile(filepath.Join("testdata", "flaky.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := filepath.Abs(filepath.Join("testdata", "flaky.list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		output string
+		code   int
+		fail   string
+	}{
+		{"flaky", string(flaky), 0, "FAIL: 0"},
+		{"flaky and failed", "--- FAIL: TestBroken (0.00s)\nFAIL\nFAIL\texample.com/z\t0.010s\n" + string(flaky), 1, "FAIL: 4"},
+		{"flaky and go error", "go: example.com/missing@v1.0.0: not found\n" + string(flaky), 1, "FAIL: 0"},
+		{"flaky subtest and failed sibling", "--- FAIL: TestP (0.00s)\n    --- FAIL: TestP/flaky (0.00s)\n    --- FAIL: TestP/broken (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\nFAIL\n", 1, "FAIL: 5"},
+		{"failed sibling and flaky subtest", "--- FAIL: TestP (0.00s)\n    --- FAIL: TestP/broken (0.00s)\n    --- FAIL: TestP/flaky (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\nFAIL\n", 1, "FAIL: 5"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			script := "cat <<'EOF'\n" + tt.output + "EOF\nexit 1\n"
+			cmd, cleanup := stubGo(t, script, "-color", "never", "-flaky-list", list)
+			defer cleanup()
+			out, err := cmd.Output()
+			if code := exitCodeOf(t, err); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if !strings.Contains(string(out), "\n"+tt.fail+"\n") {
+				t.Errorf("output doesn't count %q:\n%s", tt.fail, out)
+			}
+		})
+	}
+}
+
+func TestFastChild(t *testing.T) {
+	// go test exits as soon as it has written its output, racing
+	// with gotest reading it.
+	script := "printf '=== RUN   TestA\\n--- PASS: TestA (0.00s)\\n=== RUN   TestB\\n--- FAIL: TestB (0.00s)\\nFAIL\\nFAIL\\texample.com/x\\t0.001s\\n'\nexit 1\n"
+	for i := 0; i < 20; i++ {
+		cmd, cleanup := stubGo(t, script, "-color", "never", "-v")
+		out, err := cmd.Output()
+		cleanup()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Fatalf("exit code = %d, want 1", code)
+		}
+		for _, want := range []string{"--- PASS: TestA", "--- FAIL: TestB", "FAIL\texample.com/x", "PASS: 1\n", "FAIL: 3\n"} {
+			if !strings.Contains(string(out), want) {
+				t.Fatalf("run %d: output lacks %q:\n%s", i, want, out)
+			}
+		}
+	}
+}
+
+func TestTeardownOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	torndown, pid := filepath.Join(dir, "torndown"), filepath.Join(dir, "pid")
+	script := "echo $$ > " + pid + "\nwhile :; do sleep 0.1; done\n"
+	// Without relaying the signal, gotest is killed by it before
+	// go test exits.
+	cmd, cleanup := stubGo(t, script, "-no-signal-forward", "-after", "touch "+torndown)
+	defer cleanup()
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer killStub(pid)
+	waitForStub(t, pid)
+	cmd.Process.Signal(syscall.SIGTERM)
+	if code, want := exitCodeOf(t, cmd.Wait()), 128+int(syscall.SIGTERM); code != want {
+		t.Errorf("exit code = %d, want %d", code, want)
+	}
+	if _, err := os.Stat(torndown); err != nil {
+		t.Errorf("-after didn't run: %v", err)
+	}
+}
+
+func TestRerunCached(t *testing.T) {
+	x := "=== RUN   TestX\n--- PASS: TestX (0.00s)\nPASS\nok  \texample.com/x\t"
+	y := "=== RUN   TestY\n--- PASS: TestY (0.00s)\nPASS\nok  \texample.com/y\t0.010s\n"
+	// Only the rerun bypasses the cache.
+	script := "case \"$*\" in\n*-count=1*) cat <<'EOF'\n" + x + "0.010s\nEOF\n;;\n*) cat <<'EOF'\n" + x + "(cached)\n" + y + "EOF\n;;\nesac\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v", "-rerun-cached", "-grid")
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\nTotal: 6\n", "\nPackages: 2\n", "\nPASS: 6\n", "\nPackage grid:\n..\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRequireTests(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		output string
+		code   int
+	}{
+		{"no match", "testing: warning: no tests to run\nPASS\nok  \texample.com/x\t0.010s [no tests to run]\n", 1},
+		{"passed", "=== RUN   TestA\n--- PASS: TestA (0.00s)\nPASS\nok  \texample.com/x\t0.010s\n", 0},
+		{"skipped", "=== RUN   TestA\n--- SKIP: TestA (0.00s)\nPASS\nok  \texample.com/x\t0.010s\n", 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, cleanup := stubGo(t, "cat <<'EOF'\n"+tt.output+"EOF\n", "-color", "never", "-v", "-require-tests")
+			defer cleanup()
+			if code := exitCodeOf(t, cmd.Run()); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestAllowNoTestFiles(t *testing.T) {
+	noTestFiles := "?   \texample.com/a\t[no test files]\n?   \texample.com/b\t[no test files]\n"
+	// Only example.com/x has tests, none of which match -run.
+	noMatch := "?   \texample.com/a\t[no test files]\ntesting: warning: no tests to run\nPASS\nok  \texample.com/x\t0.010s [no tests to run]\n"
+	for _, tt := range []struct {
+		output string
+		args   []string
+		code   int
+	}{
+		{noTestFiles, []string{"-require-tests"}, 1},
+		{noTestFiles, []string{"-require-tests", "-allow-no-test-files"}, 0},
+		{noTestFiles, []string{"-strict"}, 0},
+		{noTestFiles, []string{"-strict", "-require-tests", "-allow-no-test-files"}, 0},
+		// Tests that don't match -run still fail the run.
+		{noMatch, []string{"-require-tests", "-allow-no-test-files"}, 1},
+		{noMatch, []string{"-strict", "-allow-no-test-files"}, 1},
+	} {
+		cmd, cleanup := stubGo(t, "cat <<'EOF'\n"+tt.output+"EOF\n", append([]string{"-color", "never"}, tt.args...)...)
+		if code := exitCodeOf(t, cmd.Run()); code != tt.code {
+			t.Errorf("%q with %v: exit code = %d, want %d", tt.output, tt.args, code, tt.code)
+		}
+		cleanup()
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// One shard saved the plain output, the other -json.
+	shard1, shard2 := filepath.Join(dir, "shard1.log"), filepath.Join(dir, "shard2.jsonl")
+	plain := "=== RUN   TestA\n--- PASS: TestA (0.00s)\nPASS\nok  \texample.com/a\t0.010s\n"
+	jsonl := `{"Action":"run","Package":"example.com/b","Test":"TestB"}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"    b_test.go:7: boom\n"}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"--- FAIL: TestB (0.00s)\n"}
+{"Action":"fail","Package":"example.com/b","Test":"TestB"}
+{"Action":"output","Package":"example.com/b","Output":"FAIL\texample.com/b\t0.020s\n"}
+{"Action":"fail","Package":"example.com/b"}
+`
+	if err := ioutil.WriteFile(shard1, []byte(plain), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(shard2, []byte(jsonl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := gotestCmd("-color", "never", "-aggregate", shard1, shard2).Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	for _, want := range []string{"--- PASS: TestA", "    b_test.go:7: boom\n--- FAIL: TestB", "\nPackages: 2\n", "\nPASS: 3\n", "\nFAIL: 2\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSkipPreambleOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Only the first go test prints a preamble, as would the
+	// wrapper it runs under.
+	started := filepath.Join(dir, "started")
+	script := `case "$1" in
+list) echo example.com/a; echo example.com/b ;;
+test)
+	if [ ! -f ` + started + ` ]; then echo 'wrapper v1.0'; touch ` + started + `; fi
+	for pkg in "$@"; do
+		case "$pkg" in example.com/*) printf -- '--- PASS: TestA (0.00s)\nok  \t%s\t0.010s\n' "$pkg" ;; esac
+	done ;;
+esac
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v", "-keep-going", "-skip-preamble", "1", "./...")
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- PASS: TestA (0.00s)\nok  \texample.com/a\t0.010s\n--- PASS: TestA (0.00s)\nok  \texample.com/b\t0.010s\n"
+	if !strings.HasPrefix(string(out), want) {
+		t.Errorf("output doesn't start with:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestModuleNoiseColor(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-dry-parse", filepath.Join("testdata", "download.txt")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Module resolution is tinted as information, not as an error.
+	want := fmt.Sprintf("\033[%dmgo: downloading github.com/fatih/color v1.9.0\n\033[0m", neutral)
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the tinted module line %q:\n%q", want, out)
+	}
+}
+
+func TestColorFlags(t *testing.T) {
+	for _, tt := range []struct {
+		palette    string
+		args       []string
+		pass, fail color.Attribute
+	}{
+		{pass: color.FgGreen, fail: color.FgHiRed},
+		{args: []string{"-pass-color", "blue", "-fail-color", "magenta"}, pass: color.FgBlue, fail: color.FgMagenta},
+		{palette: "magenta,cyan", pass: color.FgCyan, fail: color.FgMagenta},
+		{palette: "magenta,cyan", args: []string{"-fail-color", "blue"}, pass: color.FgCyan, fail: color.FgBlue},
+		{palette: "magenta,cyan", args: []string{"-pass-color", "hiwhite", "-fail-color", "yellow"}, pass: color.FgHiWhite, fail: color.FgYellow},
+	} {
+		t.Run(fmt.Sprint(tt.palette, tt.args), func(t *testing.T) {
+			cmd := gotestCmd(append([]string{"-color", "always", "-dry-parse", filepath.Join("testdata", "fail.txt")}, tt.args...)...)
+			if tt.palette != "" {
+				cmd.Env = append(cmd.Env, paletteEnv+"="+tt.palette)
+			}
+			out, err := cmd.Output()
+			if code := exitCodeOf(t, err); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			for _, want := range []string{
+				fmt.Sprintf("\033[%dm--- PASS: TestA (0.00s)\n", tt.pass),
+				fmt.Sprintf("\033[%dm--- FAIL: TestB (0.00s)\n", tt.fail),
+			} {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("output lacks %q:\n%q", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestColorFlagsUnknown(t *testing.T) {
+	out, err := gotestCmd("-skip-color", "orange", "-dry-parse", filepath.Join("testdata", "skip.txt")).CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := `unknown color "orange"`; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	for _, tt := range []struct {
+		ci, mode string
+		colored  bool
+	}{
+		{ci: "", mode: "auto", colored: false},
+		{ci: "true", mode: "auto", colored: true},
+		{ci: "", mode: "always", colored: true},
+		{ci: "true", mode: "never", colored: false},
+	} {
+		t.Run(tt.mode+"/CI="+tt.ci, func(t *testing.T) {
+			// The output is a pipe, so only CI detection or
+			// -color always turn colors on.
+			cmd := gotestCmd("-color", tt.mode, "-dry-parse", filepath.Join("testdata", "skip.txt"))
+			cmd.Env = append(cmd.Env, "CI="+tt.ci)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if colored := strings.Contains(string(out), "\033["); colored != tt.colored {
+				t.Errorf("colored = %v, want %v:\n%q", colored, tt.colored, out)
+			}
+		})
+	}
+}
+
+func TestColorModeInvalid(t *testing.T) {
+	out, err := gotestCmd("-color", "sometimes", "-dry-parse", filepath.Join("testdata", "skip.txt")).CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := `invalid -color "sometimes"`; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	out, err := gotestCmd("-template", "{{.Status", "-dry-parse", filepath.Join("testdata", "fail.jsonl")).CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := "unclosed action"; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestGoWarningColor(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-dry-parse", filepath.Join("testdata", "warning.txt")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Warnings from the go command are tinted as skips, not failures.
+	want := fmt.Sprintf("\033[%dmgo: warning: \"./...\" matched no packages\n\033[0m", skip)
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the tinted warning %q:\n%q", want, out)
+	}
+}
+
+func TestStyleMinimal(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-style", "minimal", "-dry-parse", filepath.Join("testdata", "skip.txt")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the name of the package is in color.
+	want := fmt.Sprintf("ok  \t\033[%dmskipt\033[0m\t0.002s\n", pass)
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the package name in color %q:\n%q", want, out)
+	}
+	for _, line := range []string{"--- PASS: TestD (0.00s)\n", "PASS\n"} {
+		if colored := fmt.Sprintf("\033[%dm%s", pass, line); strings.Contains(string(out), colored) {
+			t.Errorf("output has %q in color:\n%q", line, out)
+		}
+	}
+}
+
+func TestFailOnOutputInvalid(t *testing.T) {
+	out, err := gotestCmd("-fail-on-output", "(", "-dry-parse", filepath.Join("testdata", "skip.txt")).CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := "missing closing )"; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestTreeColors(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-tree", "-dry-parse", filepath.Join("testdata", "subtests.txt")).Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	colored := func(c color.Attribute, line string) string {
+		return fmt.Sprintf("\033[%dm%s\n\033[0m", c, line)
+	}
+	// Parents are in the color of their tree as a whole, and
+	// subtests in that of their own result.
+	want := colored(fail, "--- FAIL: TestX (0.00s)") +
+		colored(pass, "    --- PASS: TestX/case1 (0.00s)") +
+		colored(fail, "    --- FAIL: TestX/case2 (0.00s)")
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks the colored tree %q:\n%q", want, out)
+	}
+	if want := colored(pass, "--- PASS: TestY (0.00s)"); !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%q", want, out)
+	}
+}
+
+// goTestArgs returns the arguments the stub go test printed,
+// one per "arg: " line.
+func goTestArgs(out []byte) []string {
+	var args []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "arg: ") {
+			args = append(args, strings.TrimPrefix(line, "arg: "))
+		}
+	}
+	return args
+}
+
+// argsScript is a stub go test printing every argument it gets
+// on a line of its own.
+const argsScript = `shift; for arg in "$@"; do echo "arg: $arg"; done; printf 'ok  \texample.com/a\t0.010s\n'`
+
+func TestParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "gotestrc")
+	if err := ioutil.WriteFile(config, []byte("-p 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		config string
+		args   []string
+		want   []string
+		p      string
+	}{
+		{args: []string{"./..."}, want: []string{"./..."}},
+		{args: []string{"-p", "4", "./..."}, want: []string{"-p=4", "./..."}, p: "4"},
+		{args: []string{"-p=4", "-run", "TestA", "-p", "2", "./..."}, want: []string{"-p=2", "-run", "TestA", "./..."}, p: "2"},
+		{config: config, args: []string{"./..."}, want: []string{"-p=3", "./..."}, p: "3"},
+		{config: config, args: []string{"-p", "5", "./..."}, want: []string{"-p=5", "./..."}, p: "5"},
+	} {
+		t.Run(fmt.Sprint(tt.config != "", tt.args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, argsScript, append([]string{"-color", "never"}, tt.args...)...)
+			defer cleanup()
+			if tt.config != "" {
+				cmd.Env = append(cmd.Env, configEnv+"="+tt.config)
+			}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := goTestArgs(out); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("go test got args %q, want %q", got, tt.want)
+			}
+			if tt.p == "" {
+				if strings.Contains(string(out), "Parallelism:") {
+					t.Errorf("summary reports a parallelism:\n%s", out)
+				}
+			} else if want := "\nParallelism: " + tt.p + " packages\n"; !strings.Contains(string(out), want) {
+				t.Errorf("output lacks %q:\n%s", want, out)
+			}
+		})
+	}
+}
+
+func TestNocache(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"./..."}, want: []string{"./..."}},
+		{args: []string{"-nocache", "./..."}, want: []string{"-count=1", "./..."}},
+		{args: []string{"-nocache", "-count=3", "./..."}, want: []string{"-count=3", "./..."}},
+		{args: []string{"-count", "2", "-nocache", "./..."}, want: []string{"-count", "2", "./..."}},
+	} {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, argsScript, append([]string{"-color", "never"}, tt.args...)...)
+			defer cleanup()
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := goTestArgs(out); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("go test got args %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessibleColors(t *testing.T) {
+	out, err := gotestCmd("-color", "always", "-accessible", "-dry-parse", filepath.Join("testdata", "cache.txt")).Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	// Results are told apart by their labels, with no color.
+	i := strings.Index(string(out), "Summary:")
+	if i < 0 {
+		t.Fatalf("output lacks the summary:\n%q", out)
+	}
+	// The summary starts with a color of its own.
+	results := string(out[:strings.LastIndex(string(out[:i]), "\n")+1])
+	if strings.Contains(strings.Replace(results, "\033[0m", "", -1), "\033[") {
+		t.Errorf("results are in color:\n%q", results)
+	}
+	for _, want := range []string{"[PASS] ok  \texample.com/cache/b\t0.102s\n", "[SKIP] ?   \texample.com/cache/d", "[FAIL] --- FAIL: TestE (0.00s)\n"} {
+		if !strings.Contains(results, want) {
+			t.Errorf("results lack %q:\n%s", want, results)
+		}
+	}
+}
+
+func TestSkipNoTests(t *testing.T) {
+	const noTests = "?   \texample.com/cache/d\t[no test files]\n"
+	for _, tt := range []struct {
+		env      string
+		shown    bool
+		packages string
+	}{
+		{env: "false", shown: true, packages: "7"},
+		{env: "true", shown: false, packages: "6"},
+	} {
+		cmd := gotestCmd("-color", "never", "-dry-parse", filepath.Join("testdata", "cache.txt"))
+		cmd.Env = append(cmd.Env, skipNoTestsEnv+"="+tt.env)
+		out, err := cmd.Output()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Errorf("%s=%s: exit code = %d, want 1", skipNoTestsEnv, tt.env, code)
+		}
+		if shown := strings.Contains(string(out), noTests); shown != tt.shown {
+			t.Errorf("%s=%s: package without test files shown = %v, want %v:\n%s", skipNoTestsEnv, tt.env, shown, tt.shown, out)
+		}
+		// It ran no tests, so it is left out of the test counts
+		// either way.
+		want := "Total: 9\nPackages: " + tt.packages + "\nCache: 4/6 packages cached (67%)\nPASS: 5\nSKIP: 0\nFAIL: 4\n"
+		if !strings.Contains(string(out), want) {
+			t.Errorf("%s=%s: output lacks %q:\n%s", skipNoTestsEnv, tt.env, want, out)
+		}
+	}
+}
+
+func TestColorScope(t *testing.T) {
+	for _, tt := range []struct {
+		scope string
+		want  []string
+	}{
+		{
+			scope: "line",
+			want: []string{
+				fmt.Sprintf("\033[%dm--- PASS: TestA (0.00s)\n", pass),
+				fmt.Sprintf("\033[%dm--- FAIL: TestB (0.00s)\n", fail),
+				fmt.Sprintf("\033[%dmFAIL\tgithub.com/rakyll/gotest/example\t1.002s\n", fail),
+			},
+		},
+		{
+			// Only the status token is in color.
+			scope: "marker",
+			want: []string{
+				fmt.Sprintf("\033[%dm--- PASS:\033[0m TestA (0.00s)\n", pass),
+				fmt.Sprintf("\033[%dm--- FAIL:\033[0m TestB (0.00s)\n", fail),
+				fmt.Sprintf("\033[%dmFAIL\033[0m\tgithub.com/rakyll/gotest/example\t1.002s\n", fail),
+			},
+		},
+	} {
+		out, err := gotestCmd("-color", "always", "-color-scope", tt.scope, "-dry-parse", filepath.Join("testdata", "fail.txt")).Output()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Errorf("-color-scope %s: exit code = %d, want 1", tt.scope, code)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("-color-scope %s: output lacks %q:\n%q", tt.scope, want, out)
+			}
+		}
+	}
+}
+
+func TestPackageHue(t *testing.T) {
+	// The hash picks the same hue in every run.
+	for pkg, want := range map[string]color.Attribute{
+		"example.com/a": color.FgHiCyan,
+		"example.com/b": color.FgYellow,
+		"example.com/c": color.FgBlue,
+	} {
+		if got := packageHue(pkg); got != want {
+			t.Errorf("packageHue(%q) = %d, want %d", pkg, got, want)
+		}
+	}
+}
+
+func TestColorByPackage(t *testing.T) {
+	run := func() string {
+		out, err := gotestCmd("-color", "always", "-color-by-package", "-dry-parse", filepath.Join("testdata", "focus.txt")).Output()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Errorf("exit code = %d, want 1", code)
+		}
+		return string(out)
+	}
+	out := run()
+	// The marker is colored by the result, the package by its hue.
+	for _, want := range []string{
+		fmt.Sprintf("\033[%dmok\033[0m  \t\033[%dmexample.com/a\033[0m\t0.010s\n", pass, packageHue("example.com/a")),
+		fmt.Sprintf("\033[%dmFAIL\033[0m\t\033[%dmexample.com/b\033[0m\t0.010s\n", fail, packageHue("example.com/b")),
+		fmt.Sprintf("\033[%dmok\033[0m  \t\033[%dmexample.com/c\033[0m\t0.010s\n", pass, packageHue("example.com/c")),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%q", want, out)
+		}
+	}
+	if again := run(); again != out {
+		t.Errorf("a second run colored the output differently:\n%q\nwant:\n%q", again, out)
+	}
+}
+
+func TestMapPathInvalid(t *testing.T) {
+	for _, v := range []string{"/app", "=/home/me/project", "/app="} {
+		out, err := gotestCmd("-map-path", v, "-dry-parse", filepath.Join("testdata", "paths.txt")).CombinedOutput()
+		if code := exitCodeOf(t, err); code != 2 {
+			t.Errorf("-map-path %s: exit code = %d, want 2", v, code)
+		}
+		if want := fmt.Sprintf("invalid value %q for flag -map-path: want from=to", v); !strings.Contains(string(out), want) {
+			t.Errorf("-map-path %s: output lacks %q:\n%s", v, want, out)
+		}
+	}
+}
+
+func TestCleanSkipNoTests(t *testing.T) {
+	cmd := gotestCmd("-color", "never", "-clean", "-dry-parse", filepath.Join("testdata", "noisy.txt"))
+	cmd.Env = append(cmd.Env, skipNoTestsEnv+"=false")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "?   \texample.com/b\t[no test files]\n"; !strings.Contains(string(out), want) {
+		t.Errorf("%s=false didn't override -clean, output lacks %q:\n%s", skipNoTestsEnv, want, out)
+	}
+}
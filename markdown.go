@@ -0,0 +1,73 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// markdownReport writes the summary as Markdown to a file,
+// for posting as a pull request comment. Failures are listed
+// in a collapsed block, which GitHub renders as <details>.
+// With append, the summary is added to the end of the file,
+// as GitHub Actions expects of $GITHUB_STEP_SUMMARY.
+type markdownReport struct {
+	path   string
+	append bool
+}
+
+type markdownPage struct {
+	Summary  jsonSummary
+	Failures []testResult
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`### {{if .Summary.Fail}}:x: Tests failed{{else}}:white_check_mark: Tests passed{{end}}
+
+| Total | Passed | Skipped | Failed | Elapsed |
+| ----: | -----: | ------: | -----: | ------: |
+| {{.Summary.Total}} | {{.Summary.Pass}} | {{.Summary.Skip}} | {{.Summary.Fail}} | {{printf "%.2f" .Summary.Elapsed}}s |
+{{if .Failures}}
+<details>
+<summary>{{len .Failures}} failed {{if eq (len .Failures) 1}}test{{else}}tests{{end}}</summary>
+{{range .Failures}}
+#### ` + "`" + `{{if .Package}}{{.Package}}.{{end}}{{.Name}}` + "`" + `
+{{if .Output}}
+` + "```" + `
+{{join .Output "\n"}}
+` + "```" + `
+{{end}}{{end}}
+</details>
+{{end}}`))
+
+func (m markdownReport) report(summary *ResultSummary) error {
+	page := markdownPage{Summary: newJSONSummary(summary)}
+	for _, t := range summary.tests {
+		if t.Status == "FAIL" {
+			page.Failures = append(page.Failures, t)
+		}
+	}
+	var buf bytes.Buffer
+	if err := markdownTemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+	if !m.append {
+		return ioutil.WriteFile(m.path, buf.Bytes(), 0644)
+	}
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// metricsTimeout bounds the time spent pushing metrics.
+const metricsTimeout = 5 * time.Second
+
+// pusher pushes the metrics of a run if -metrics-url is set.
+var pusher metricsPusher
+
+// metricsPusher pushes the final counts of a run
+// to a metrics system.
+type metricsPusher interface {
+	push(summary *ResultSummary) error
+}
+
+// loadPusher picks the pusher for the -metrics-url scheme:
+// statsd:// or udp:// for statsd, http:// or https:// for
+// a Prometheus pushgateway.
+func loadPusher() error {
+	if *metricsURL == "" {
+		return nil
+	}
+	u, err := url.Parse(*metricsURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "statsd", "udp":
+		pusher = statsdPusher{addr: u.Host}
+	case "http", "https":
+		pusher = pushgatewayPusher{url: *metricsURL}
+	default:
+		return fmt.Errorf("unsupported -metrics-url scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// pushMetrics pushes the metrics of summary. Failing to
+// push them doesn't fail the run.
+func pushMetrics(summary *ResultSummary) {
+	if pusher == nil {
+		return
+	}
+	if err := pusher.push(summary); err != nil {
+		log.Printf("warning: pushing metrics: %v", err)
+	}
+}
+
+// statsdPusher sends gauges to a statsd server over UDP.
+type statsdPusher struct {
+	addr string
+}
+
+func (p statsdPusher) push(summary *ResultSummary) error {
+	conn, err := net.DialTimeout("udp", p.addr, metricsTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(statsdPayload(summary))
+	return err
+}
+
+func statsdPayload(summary *ResultSummary) []byte {
+	var buf bytes.Buffer
+	c := summary.snapshot()
+	fmt.Fprintf(&buf, "gotest.pass:%d|g\n", c.pass)
+	fmt.Fprintf(&buf, "gotest.fail:%d|g\n", c.fail)
+	fmt.Fprintf(&buf, "gotest.skip:%d|g\n", c.skipped)
+	fmt.Fprintf(&buf, "gotest.elapsed:%d|g\n", summary.elapsed.Milliseconds())
+	return buf.Bytes()
+}
+
+// pushgatewayPusher posts gauges to a Prometheus pushgateway.
+// Its url includes the job, as in http://host:9091/metrics/job/gotest.
+type pushgatewayPusher struct {
+	url string
+}
+
+func (p pushgatewayPusher) push(summary *ResultSummary) error {
+	client := &http.Client{Timeout: metricsTimeout}
+	resp, err := client.Post(p.url, "text/plain; version=0.0.4", bytes.NewReader(pushgatewayPayload(summary)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func pushgatewayPayload(summary *ResultSummary) []byte {
+	var buf bytes.Buffer
+	c := summary.snapshot()
+	buf.WriteString("# TYPE gotest_tests gauge\n")
+	fmt.Fprintf(&buf, "gotest_tests{result=\"pass\"} %d\n", c.pass)
+	fmt.Fprintf(&buf, "gotest_tests{result=\"fail\"} %d\n", c.fail)
+	fmt.Fprintf(&buf, "gotest_tests{result=\"skip\"} %d\n", c.skipped)
+	buf.WriteString("# TYPE gotest_elapsed_seconds gauge\n")
+	fmt.Fprintf(&buf, "gotest_elapsed_seconds %g\n", summary.elapsed.Seconds())
+	return buf.Bytes()
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var metricsSummary = &ResultSummary{pass: 3, fail: 1, skipped: 2, elapsed: 1500 * time.Millisecond}
+
+func TestStatsdPusher(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p := statsdPusher{addr: conn.LocalAddr().String()}
+	if err := p.push(metricsSummary); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "gotest.pass:3|g\ngotest.fail:1|g\ngotest.skip:2|g\ngotest.elapsed:1500|g\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("statsd got %q, want %q", got, want)
+	}
+}
+
+func TestPushgatewayPusher(t *testing.T) {
+	var method, path, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+	}))
+	defer ts.Close()
+
+	p := pushgatewayPusher{url: ts.URL + "/metrics/job/gotest"}
+	if err := p.push(metricsSummary); err != nil {
+		t.Fatal(err)
+	}
+	if method != "POST" || path != "/metrics/job/gotest" {
+		t.Errorf("got %s %s, want POST /metrics/job/gotest", method, path)
+	}
+	want := `# TYPE gotest_tests gauge
+gotest_tests{result="pass"} 3
+gotest_tests{result="fail"} 1
+gotest_tests{result="skip"} 2
+# TYPE gotest_elapsed_seconds gauge
+gotest_elapsed_seconds 1.5
+`
+	if body != want {
+		t.Errorf("pushgateway got:\n%s\nwant:\n%s", body, want)
+	}
+}
+
+func TestPushgatewayPusherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	p := pushgatewayPusher{url: ts.URL + "/metrics/job/gotest"}
+	if err := p.push(metricsSummary); err == nil {
+		t.Error("push succeeded, want the error status reported")
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpTimeout bounds the time spent exporting spans.
+const otlpTimeout = 5 * time.Second
+
+// OTLP span kinds and status codes, from the OpenTelemetry
+// protocol.
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// otlpTraces is an OTLP/HTTP trace export request, in the JSON
+// encoding of the protocol, which needs no dependencies.
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes"`
+	Status       otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code,omitempty"`
+}
+
+// exportSpans exports the tests of the run as spans to
+// -otlp-endpoint, each under the span of its package, or of
+// its parent test for subtests. Failing to export them
+// doesn't fail the run.
+func exportSpans(summary *ResultSummary) {
+	if *otlpEndpoint == "" || len(summary.tests) == 0 {
+		return
+	}
+	traces, err := newTraces(summary.tests)
+	if err == nil {
+		err = otlpPost(*otlpEndpoint, traces)
+	}
+	if err != nil {
+		log.Printf("warning: exporting spans: %v", err)
+	}
+}
+
+// newTraces returns the spans of tests, all in one trace.
+func newTraces(tests []testResult) (otlpTraces, error) {
+	traceID, err := randomHex(16)
+	if err != nil {
+		return otlpTraces{}, err
+	}
+	// Subtests finish before their parent test, so the IDs of
+	// all the spans are needed up front.
+	ids := make(map[string]string)
+	for _, t := range tests {
+		for _, key := range []string{t.Package, t.Package + " " + t.Name} {
+			if ids[key] != "" {
+				continue
+			}
+			if ids[key], err = randomHex(8); err != nil {
+				return otlpTraces{}, err
+			}
+		}
+	}
+
+	type pkgSpan struct {
+		start, end time.Time
+		failed     bool
+	}
+	pkgs := make(map[string]*pkgSpan)
+	var order []string
+	var spans []otlpSpan
+	for _, t := range tests {
+		start, end := t.End.Add(-t.Elapsed), t.End
+		p := pkgs[t.Package]
+		if p == nil {
+			p = &pkgSpan{start: start, end: end}
+			pkgs[t.Package] = p
+			order = append(order, t.Package)
+		}
+		if start.Before(p.start) {
+			p.start = start
+		}
+		if end.After(p.end) {
+			p.end = end
+		}
+		p.failed = p.failed || t.Status == "FAIL"
+
+		parent := ids[t.Package]
+		if i := strings.LastIndex(t.Name, "/"); i >= 0 {
+			if id, ok := ids[t.Package+" "+t.Name[:i]]; ok {
+				parent = id
+			}
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:      traceID,
+			SpanID:       ids[t.Package+" "+t.Name],
+			ParentSpanID: parent,
+			Name:         t.Name,
+			Kind:         spanKindInternal,
+			Start:        unixNano(start),
+			End:          unixNano(end),
+			Attributes: []otlpAttribute{
+				attribute("test.name", t.Name),
+				attribute("test.package", t.Package),
+				attribute("test.status", t.Status),
+			},
+			Status: otlpStatus{spanStatus(t.Status)},
+		})
+	}
+	for _, pkg := range order {
+		p := pkgs[pkg]
+		status := "PASS"
+		if p.failed {
+			status = "FAIL"
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:    traceID,
+			SpanID:     ids[pkg],
+			Name:       pkg,
+			Kind:       spanKindInternal,
+			Start:      unixNano(p.start),
+			End:        unixNano(p.end),
+			Attributes: []otlpAttribute{attribute("test.package", pkg)},
+			Status:     otlpStatus{spanStatus(status)},
+		})
+	}
+	return otlpTraces{[]otlpResourceSpans{{
+		Resource: otlpResource{[]otlpAttribute{
+			attribute("service.name", "gotest"),
+			attribute("gotest.run_id", runID),
+		}},
+		ScopeSpans: []otlpScopeSpans{{otlpScope{"gotest"}, spans}},
+	}}}, nil
+}
+
+// spanStatus returns the status code of the span of a test
+// with status. Skipped tests are left unset.
+func spanStatus(status string) int {
+	switch status {
+	case "PASS":
+		return statusOK
+	case "FAIL":
+		return statusError
+	}
+	return 0
+}
+
+func attribute(key, value string) otlpAttribute {
+	return otlpAttribute{key, otlpValue{value}}
+}
+
+// unixNano formats t as OTLP/JSON encodes 64-bit integers.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// otlpPost posts traces to the OTLP/HTTP collector at endpoint,
+// such as http://localhost:4318, under /v1/traces unless it
+// already names that path.
+func otlpPost(endpoint string, traces otlpTraces) error {
+	url := endpoint
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url = strings.TrimSuffix(url, "/") + "/v1/traces"
+	}
+	b, err := json.Marshal(traces)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: otlpTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
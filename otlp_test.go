@@ -0,0 +1,105 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOTLPExport(t *testing.T) {
+	var path string
+	var got otlpTraces
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	end := time.Unix(100, 0)
+	tests := []testResult{
+		{Package: "example.com/x", Name: "TestA/sub", Status: "FAIL", Elapsed: time.Second, End: end.Add(-time.Second)},
+		{Package: "example.com/x", Name: "TestA", Status: "FAIL", Elapsed: 3 * time.Second, End: end},
+		{Package: "example.com/x", Name: "TestB", Status: "SKIP", End: end},
+	}
+	traces, err := newTraces(tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := otlpPost(ts.URL, traces); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/traces" {
+		t.Errorf("posted to %s, want /v1/traces", path)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("got %+v, want the spans of one resource and scope", got)
+	}
+	spans := make(map[string]otlpSpan)
+	for _, s := range got.ResourceSpans[0].ScopeSpans[0].Spans {
+		spans[s.Name] = s
+		if s.TraceID != traces.ResourceSpans[0].ScopeSpans[0].Spans[0].TraceID {
+			t.Errorf("span %s in trace %s, want all in one trace", s.Name, s.TraceID)
+		}
+	}
+	if len(spans) != 4 {
+		t.Fatalf("got spans %v, want example.com/x, TestA, TestA/sub and TestB", spans)
+	}
+	pkg, parent, sub, skipped := spans["example.com/x"], spans["TestA"], spans["TestA/sub"], spans["TestB"]
+	for _, tt := range []struct {
+		span   otlpSpan
+		parent string
+		code   int
+		start  int64
+		end    int64
+	}{
+		{pkg, "", statusError, 97, 100},
+		{parent, pkg.SpanID, statusError, 97, 100},
+		{sub, parent.SpanID, statusError, 98, 99},
+		{skipped, pkg.SpanID, 0, 100, 100},
+	} {
+		s := tt.span
+		if s.ParentSpanID != tt.parent {
+			t.Errorf("%s: parent %q, want %q", s.Name, s.ParentSpanID, tt.parent)
+		}
+		if s.Status.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", s.Name, s.Status.Code, tt.code)
+		}
+		start, end := strconv.FormatInt(tt.start*1e9, 10), strconv.FormatInt(tt.end*1e9, 10)
+		if s.Start != start || s.End != end {
+			t.Errorf("%s: from %s to %s, want from %s to %s", s.Name, s.Start, s.End, start, end)
+		}
+	}
+	want := []otlpAttribute{
+		attribute("test.name", "TestA/sub"),
+		attribute("test.package", "example.com/x"),
+		attribute("test.status", "FAIL"),
+	}
+	if len(sub.Attributes) != len(want) {
+		t.Fatalf("TestA/sub attributes %v, want %v", sub.Attributes, want)
+	}
+	for i := range want {
+		if sub.Attributes[i] != want[i] {
+			t.Errorf("TestA/sub attribute %v, want %v", sub.Attributes[i], want[i])
+		}
+	}
+}
+
+func TestOTLPPostError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	if err := otlpPost(ts.URL+"/v1/traces", otlpTraces{}); err == nil {
+		t.Error("export succeeded, want the error status reported")
+	}
+}
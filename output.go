@@ -0,0 +1,310 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// testHeaders are the prefixes of lines that name the test
+// the output following them belongs to.
+var testHeaders = []string{
+	"=== RUN", "=== CONT", "=== PAUSE", "=== NAME",
+	"--- PASS:", "--- FAIL:", "--- SKIP:",
+}
+
+// testName returns the test named by a trimmed header line
+// such as "--- FAIL: TestA (0.00s)", or "" for other lines.
+func testName(trimmed string) string {
+	for _, h := range testHeaders {
+		if strings.HasPrefix(trimmed, h) {
+			name := strings.TrimSpace(strings.TrimPrefix(trimmed, h))
+			if i := strings.Index(name, " ("); i >= 0 {
+				name = name[:i]
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// failOnOutput matches the output lines that fail the run.
+var failOnOutput *regexp.Regexp
+
+// loadFailOnOutput compiles the -fail-on-output flag.
+func loadFailOnOutput() error {
+	if *failOnOutputFlag == "" {
+		return nil
+	}
+	re, err := regexp.Compile(*failOnOutputFlag)
+	if err != nil {
+		return err
+	}
+	failOnOutput = re
+	return nil
+}
+
+// isForbidden reports whether line is output matching
+// -fail-on-output. The lines go test prints about test
+// and package results never are.
+func isForbidden(line, trimmed string) bool {
+	if failOnOutput == nil || testName(trimmed) != "" || isPackageLine(trimmed) {
+		return false
+	}
+	return failOnOutput.MatchString(line)
+}
+
+// isPackageLine reports whether a trimmed line is a package
+// result line such as "ok  pkg 0.1s" or a bare "FAIL".
+func isPackageLine(trimmed string) bool {
+	switch {
+	case trimmed == "PASS", trimmed == "FAIL":
+		return true
+	case strings.HasPrefix(trimmed, "ok "), strings.HasPrefix(trimmed, "ok\t"):
+		return true
+	case strings.HasPrefix(trimmed, "FAIL "), strings.HasPrefix(trimmed, "FAIL\t"):
+		return true
+	case strings.HasPrefix(trimmed, "? "), strings.HasPrefix(trimmed, "?\t"):
+		return true
+	}
+	return false
+}
+
+// testKey identifies a test of a package. The package is ""
+// until the end of its output, unless go test printed JSON.
+type testKey struct {
+	pkg, test string
+}
+
+// String returns the name of the test qualified by its package,
+// if known, such as example.com/foo.TestFoo.
+func (k testKey) String() string {
+	if k.pkg == "" {
+		return k.test
+	}
+	return k.pkg + "." + k.test
+}
+
+// record attributes line to the test producing output and keeps
+// it until the test passes or is skipped, so the full output of
+// failed tests is available once the run is over.
+func (r *ResultSummary) record(line, trimmed string) {
+	name := testName(trimmed)
+	isHeader := name != ""
+	if isHeader {
+		r.current = name
+	} else if isPackageLine(trimmed) {
+		r.current = ""
+	}
+	if r.current == "" {
+		return
+	}
+	if !isHeader {
+		if r.pkgLines == nil {
+			r.pkgLines = make(map[testKey]int)
+		}
+		r.pkgLines[testKey{r.pkg, r.current}]++
+	}
+	if r.output == nil {
+		r.output = make(map[string][]string)
+	}
+	r.output[r.current] = append(r.output[r.current], line)
+
+	if status := resultStatus(trimmed); status != "" {
+		elapsed, _ := testElapsed(trimmed)
+		end := r.eventTime
+		if end.IsZero() {
+			end = time.Now()
+		}
+		r.tests = append(r.tests, testResult{
+			Package: r.pkg,
+			Name:    r.current,
+			Status:  status,
+			Elapsed: elapsed,
+			End:     end,
+			Reason:  skipMessage(status, r.output[r.current]),
+		})
+		sendEvent(liveEvent{"test", r.pkg, r.current, status, elapsed.Seconds(), runID})
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "--- PASS:"), strings.HasPrefix(trimmed, "--- SKIP:"):
+		delete(r.output, r.current)
+	case strings.HasPrefix(trimmed, "--- FAIL:"):
+		for _, test := range r.failed {
+			if test == r.current {
+				return
+			}
+		}
+		r.failed = append(r.failed, r.current)
+	}
+}
+
+// resultStatus returns PASS, FAIL or SKIP for a trimmed test
+// result line, or "" for other lines.
+func resultStatus(trimmed string) string {
+	for _, h := range resultHeaders {
+		if strings.HasPrefix(trimmed, h) {
+			return strings.TrimSuffix(strings.TrimPrefix(h, "--- "), ":")
+		}
+	}
+	return ""
+}
+
+// failuresReport writes the output of every failed test
+// to a file, one block per test.
+type failuresReport struct {
+	path string
+}
+
+func (f failuresReport) report(summary *ResultSummary) error {
+	var buf bytes.Buffer
+	n := 0
+	for _, t := range summary.tests {
+		if t.Status != "FAIL" {
+			continue
+		}
+		if n > 0 {
+			buf.WriteString("\n")
+		}
+		n++
+		for _, line := range t.Output {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+	}
+	return ioutil.WriteFile(f.path, buf.Bytes(), 0644)
+}
+
+// failuresJSONLReport writes every failed test with its output
+// as a line of JSON to a file, for dashboards of failures.
+type failuresJSONLReport struct {
+	path string
+}
+
+// jsonlFailure is a line of the -jsonl-failures report.
+type jsonlFailure struct {
+	RunID   string   `json:"run_id"`
+	Package string   `json:"package"`
+	Test    string   `json:"test"`
+	Elapsed float64  `json:"elapsed"`
+	Output  []string `json:"output"`
+}
+
+func (f failuresJSONLReport) report(summary *ResultSummary) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range summary.tests {
+		if t.Status != "FAIL" {
+			continue
+		}
+		output := t.Output
+		if output == nil {
+			output = []string{}
+		}
+		if err := enc.Encode(jsonlFailure{runID, t.Package, t.Name, t.Elapsed.Seconds(), output}); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(f.path, buf.Bytes(), 0644)
+}
+
+// printNoisiest prints the n tests that printed the most lines,
+// qualified by their package.
+func (r *ResultSummary) printNoisiest(n int) {
+	tests := make([]testKey, 0, len(r.lines))
+	for test := range r.lines {
+		tests = append(tests, test)
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if r.lines[tests[i]] != r.lines[tests[j]] {
+			return r.lines[tests[i]] > r.lines[tests[j]]
+		}
+		return tests[i].String() < tests[j].String()
+	})
+	if len(tests) > n {
+		tests = tests[:n]
+	}
+	if len(tests) == 0 {
+		return
+	}
+	color.Cyan("Noisiest tests:")
+	for _, test := range tests {
+		color.White("  %s: %d lines", test, r.lines[test])
+	}
+}
+
+// heldLine is a line of output held back by -failures-last.
+type heldLine struct {
+	line string
+	c    color.Attribute
+}
+
+// hold holds back line, which is to be printed in color c,
+// until the outcome of the test it belongs to is known. Lines
+// of tests that pass or are skipped are then printed, while
+// those of failed tests are held until the end of the run.
+// It reports whether line was held.
+func (r *ResultSummary) hold(line, trimmed string, c color.Attribute) bool {
+	test := r.current
+	if test == "" || r.released[test] {
+		return false
+	}
+	if r.held == nil {
+		r.held = make(map[string][]heldLine)
+		r.released = make(map[string]bool)
+	}
+	if _, ok := r.held[test]; !ok {
+		r.heldOrder = append(r.heldOrder, test)
+	}
+	r.held[test] = append(r.held[test], heldLine{line, c})
+
+	if strings.HasPrefix(trimmed, "--- PASS:") || strings.HasPrefix(trimmed, "--- SKIP:") {
+		r.release(test)
+	}
+	return true
+}
+
+// release prints the output held back for test, which passed or
+// was skipped, and holds back none of its output from then on.
+func (r *ResultSummary) release(test string) {
+	if r.released == nil {
+		r.released = make(map[string]bool)
+	}
+	r.released[test] = true
+	for _, l := range r.held[test] {
+		r.println(l.line, l.c)
+	}
+	delete(r.held, test)
+}
+
+// releaseHeld prints the output still held back at the end of
+// the run: that of tests that never finished, followed by that
+// of failed tests.
+func (r *ResultSummary) releaseHeld() {
+	failed := make(map[string]bool)
+	for _, test := range r.failed {
+		failed[test] = true
+	}
+	for _, last := range []bool{false, true} {
+		for _, test := range r.heldOrder {
+			if failed[test] != last {
+				continue
+			}
+			for _, l := range r.held[test] {
+				r.println(l.line, l.c)
+			}
+		}
+	}
+	r.held, r.heldOrder = nil, nil
+}
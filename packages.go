@@ -0,0 +1,106 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// packageResult returns the status ("ok", "FAIL" or "?") and
+// import path reported by a trimmed package result line such
+// as "ok  example.com/foo 0.1s".
+func packageResult(trimmed string) (status, pkg string, ok bool) {
+	f := strings.Fields(trimmed)
+	if len(f) < 2 {
+		return "", "", false
+	}
+	switch f[0] {
+	case "ok", "FAIL", "?":
+		return f[0], f[1], true
+	}
+	return "", "", false
+}
+
+// trackPackage records the result of the package reported
+// by a trimmed package result line.
+func (r *ResultSummary) trackPackage(trimmed string) {
+	status, pkg, ok := packageResult(trimmed)
+	if !ok {
+		return
+	}
+	if r.rerunCached && isCached(trimmed) {
+		r.forgetPackage()
+		r.forgetLines(pkg)
+		r.cachedPackages = append(r.cachedPackages, pkg)
+		return
+	}
+	r.endPackage(pkg)
+	if strings.Contains(trimmed, "[no test files]") {
+		r.noTestFiles++
+		if skipnotest {
+			return
+		}
+	}
+	r.seePackage(pkg)
+	if d, ok := packageElapsed(trimmed); ok {
+		r.packagesElapsed += d
+	}
+	if status != "?" {
+		r.testedPackages++
+	}
+	if isCached(trimmed) {
+		r.cachedPackages = append(r.cachedPackages, pkg)
+	}
+	sendEvent(liveEvent{Type: "package", Package: pkg, Status: packageStatuses[status], RunID: runID})
+	switch {
+	case status == "FAIL" && r.pkgFailures == 0 && r.pkgFlaky > 0:
+		r.flakyPackages = append(r.flakyPackages, pkg)
+		status = "flaky"
+	case status == "FAIL":
+		r.failedPackages = append(r.failedPackages, pkg)
+		r.stopAfter(pkg)
+	}
+	r.pkgStatuses = append(r.pkgStatuses, status)
+	switch {
+	case r.pkgNoMatch || strings.HasSuffix(trimmed, "[no tests to run]"):
+		r.noMatch++
+	case status == "ok":
+		r.ranPackages++
+	}
+	r.pkgFailures, r.pkgFlaky, r.pkgNoMatch = 0, 0, false
+	r.pkgFailed = nil
+}
+
+// endPackage completes the results of the tests of pkg, whose
+// output is over, with their package and the output of those
+// that failed.
+func (r *ResultSummary) endPackage(pkg string) {
+	for i := r.pkgStart; i < len(r.tests); i++ {
+		t := &r.tests[i]
+		if t.Package == "" {
+			t.Package = pkg
+		}
+		if t.Status == "FAIL" {
+			t.Output = r.output[t.Name]
+		}
+	}
+	for i := r.pkgStart; i < len(r.tests); i++ {
+		delete(r.output, r.tests[i].Name)
+	}
+	r.pkgStart = len(r.tests)
+	r.endLines(pkg)
+	for i := r.sortStart; i < len(r.sorted); i++ {
+		if r.sorted[i].pkg == "" {
+			r.sorted[i].pkg = pkg
+		}
+	}
+	r.sortStart = len(r.sorted)
+}
+
+// seePackage adds pkg to the packages tested.
+func (r *ResultSummary) seePackage(pkg string) {
+	if r.packages == nil {
+		r.packages = make(map[string]bool)
+	}
+	r.packages[pkg] = true
+}
@@ -0,0 +1,35 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDistinctPackages(t *testing.T) {
+	r := &ResultSummary{}
+	for _, line := range []string{
+		"ok  	example.com/a	0.010s",
+		"--- FAIL: TestB (0.00s)",
+		"FAIL",
+		"FAIL	example.com/b	0.020s",
+		"?   	example.com/c	[no test files]",
+		"ok  	example.com/a	(cached)",
+		"FAIL	example.com/b	0.020s",
+		"FAIL",
+	} {
+		r.trackPackage(line)
+	}
+	var got []string
+	for pkg := range r.packages {
+		got = append(got, pkg)
+	}
+	sort.Strings(got)
+	if want := "example.com/a example.com/b example.com/c"; strings.Join(got, " ") != want {
+		t.Errorf("packages = %q, want %s", got, want)
+	}
+}
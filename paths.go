@@ -0,0 +1,48 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// pathMap rewrites the paths under from to be under to.
+type pathMap struct {
+	from *regexp.Regexp
+	to   string
+}
+
+// pathMaps is the -map-path flag, which can be repeated.
+type pathMaps []pathMap
+
+func (m *pathMaps) String() string {
+	return ""
+}
+
+// Set adds a mapping given as from=to. Only whole path
+// elements match, so /app doesn't rewrite /application.
+func (m *pathMaps) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return errors.New("want from=to")
+	}
+	from := strings.TrimSuffix(v[:i], "/")
+	to := strings.TrimSuffix(v[i+1:], "/")
+	re := regexp.MustCompile(`(^|[^\w./-])` + regexp.QuoteMeta(from) + `(/|$|[:\s])`)
+	*m = append(*m, pathMap{re, strings.Replace(to, "$", "$$", -1)})
+	return nil
+}
+
+// mapPaths rewrites the paths in line as -map-path asks, such
+// as from those in a container to those on the host, so that
+// editors can open the files at the lines printed.
+func mapPaths(line string) string {
+	for _, m := range pathMapping {
+		line = m.from.ReplaceAllString(line, "${1}"+m.to+"${2}")
+	}
+	return line
+}
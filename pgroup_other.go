@@ -0,0 +1,25 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// ownProcessGroup returns a function signaling cmd, as process
+// groups are not supported on this platform.
+func ownProcessGroup(cmd *exec.Cmd) func(os.Signal) {
+	return func(sig os.Signal) { cmd.Process.Signal(sig) }
+}
+
+// testsSignaler returns a function signaling cmd, as the
+// processes it starts can't be told apart on this platform.
+func testsSignaler(cmd *exec.Cmd) func(os.Signal) error {
+	return func(sig os.Signal) error { return cmd.Process.Signal(sig) }
+}
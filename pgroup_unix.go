@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ownProcessGroup starts cmd in a process group of its own and
+// returns a function signaling the whole group, so that the
+// signals reach the test binaries go test runs.
+func ownProcessGroup(cmd *exec.Cmd) func(os.Signal) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return func(sig os.Signal) {
+		s, ok := sig.(syscall.Signal)
+		if !ok {
+			cmd.Process.Signal(sig)
+			return
+		}
+		syscall.Kill(-cmd.Process.Pid, s)
+	}
+}
+
+// testsSignaler returns a function signaling the processes the
+// go command run by cmd started, such as test binaries, but not
+// the go command itself. Once signaled with SIGQUIT, the go
+// command no longer prints the output of the tests, which is
+// where they dump the stacks of their goroutines. The processes
+// are found with pgrep, without which none are signaled.
+func testsSignaler(cmd *exec.Cmd) func(os.Signal) error {
+	return func(sig os.Signal) error {
+		s, ok := sig.(syscall.Signal)
+		if !ok {
+			return fmt.Errorf("unsupported signal %v", sig)
+		}
+		out, err := exec.Command("pgrep", "-P", strconv.Itoa(cmd.Process.Pid)).Output()
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == 1 {
+			return errors.New("no tests are running")
+		} else if err != nil {
+			return fmt.Errorf("finding the tests with pgrep: %v", err)
+		}
+		for _, f := range strings.Fields(string(out)) {
+			if pid, err := strconv.Atoi(f); err == nil {
+				syscall.Kill(pid, s)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// formatter is the -pipe command, fed the raw output of
+// go test on its standard input.
+type formatter struct {
+	cmdline string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	err     error
+}
+
+// startFormatter starts cmdline in the shell, writing
+// to the standard output and error of gotest.
+func startFormatter(cmdline string) (*formatter, error) {
+	cmd := shellCommand(cmdline)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &formatter{cmdline: cmdline, cmd: cmd, stdin: stdin}, nil
+}
+
+// Write feeds p to the formatter. Once the formatter stops
+// reading, output is dropped, but the error is not returned:
+// a failing formatter must not stop go test.
+func (f *formatter) Write(p []byte) (int, error) {
+	if f.err == nil {
+		_, f.err = f.stdin.Write(p)
+	}
+	return len(p), nil
+}
+
+// wait closes the input of the formatter and waits for it
+// to exit, reporting if it failed.
+func (f *formatter) wait() {
+	f.stdin.Close()
+	err := f.cmd.Wait()
+	if err == nil && f.err != nil && !isBrokenPipe(f.err) {
+		err = f.err
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pipe command %q failed: %v\n", f.cmdline, err)
+	}
+}
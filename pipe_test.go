@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rawOutput is printed by the stub go test of the -pipe tests.
+const rawOutput = "=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n--- PASS: TestB (0.00s)\nPASS\nok  \texample.com/a\t0.010s\n"
+
+func TestPipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	raw := filepath.Join(dir, "raw")
+	cmd, cleanup := stubGo(t, "printf '"+rawOutput+"'",
+		"-color", "never", "-pipe", "cat > "+raw, "-v", "./...")
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The formatter gets the lines gotest hides too.
+	if string(b) != rawOutput {
+		t.Errorf("the formatter got:\n%s\nwant:\n%s", b, rawOutput)
+	}
+	if strings.Contains(string(out), "=== RUN") || !strings.Contains(string(out), "--- PASS: TestB (0.00s)\n") {
+		t.Errorf("gotest printed:\n%s", out)
+	}
+}
+
+func TestPipeFormatterFails(t *testing.T) {
+	cmd, cleanup := stubGo(t, "printf '"+rawOutput+"'",
+		"-color", "never", "-pipe", "exit 3", "./...")
+	defer cleanup()
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// The tests run to the end, and their result is the run's.
+	if err != nil {
+		t.Errorf("gotest failed: %v", err)
+	}
+	if !strings.Contains(string(out), "ok  \texample.com/a\t0.010s\n") {
+		t.Errorf("output lacks the package result:\n%s", out)
+	}
+	if want := `pipe command "exit 3" failed: exit status 3`; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr lacks %q:\n%s", want, stderr.String())
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "regexp"
+
+// preambleEnd matches the last line of the preamble.
+var preambleEnd *regexp.Regexp
+
+// loadPreambleEnd compiles the -preamble-end flag.
+func loadPreambleEnd() error {
+	if *preambleEndFlag == "" {
+		return nil
+	}
+	re, err := regexp.Compile(*preambleEndFlag)
+	if err != nil {
+		return err
+	}
+	preambleEnd = re
+	return nil
+}
+
+// A preamble drops the lines CI wrappers print before the
+// output of go test: the first -skip-preamble lines, and then
+// those up to and including the first line matching
+// -preamble-end.
+type preamble struct {
+	skipped int
+	ended   bool
+}
+
+// outputPreamble is the preamble of the output of the run. Only
+// the output read first has one: that of the next go test run
+// by gotest, as with -batch, or of the next -aggregate log,
+// starts right away.
+var outputPreamble preamble
+
+// skip reports whether line is part of the preamble.
+func (p *preamble) skip(line string) bool {
+	if p.skipped < *skipPreamble {
+		p.skipped++
+		return true
+	}
+	if preambleEnd == nil || p.ended {
+		return false
+	}
+	p.ended = preambleEnd.MatchString(line)
+	return true
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// packagePrefix is left out of the package names shown.
+var packagePrefix string
+
+// loadTrimPrefix sets packagePrefix from -trim-prefix, which
+// may be "module" for the path of the main module. A prefix
+// always ends in a slash, so that only whole path elements
+// are left out.
+func loadTrimPrefix() error {
+	p := *trimPrefix
+	if p == "module" {
+		var err error
+		if p, err = mainModule(); err != nil {
+			return fmt.Errorf("-trim-prefix module: %v", err)
+		}
+	}
+	if p != "" && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	packagePrefix = p
+	return nil
+}
+
+// mainModule returns the path of the main module. In a
+// workspace, the first module is as good a guess as any.
+func mainModule() (string, error) {
+	gomod, err := goOutput("env", "GOMOD")
+	if err != nil {
+		return "", err
+	}
+	if gomod == "" || gomod == os.DevNull {
+		return "", fmt.Errorf("no go.mod file in the working directory or its parents")
+	}
+	path, err := goOutput("list", "-m")
+	if err != nil {
+		return "", err
+	}
+	return strings.Fields(path)[0], nil
+}
+
+// goOutput runs the go command with args and returns its
+// output, or its error message if it fails.
+func goOutput(args ...string) (string, error) {
+	out, err := exec.Command("go", args...).Output()
+	if ee, ok := err.(*exec.ExitError); ok {
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(ee.Stderr)))
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// trimPackage leaves packagePrefix out of s, a package or a
+// name qualified by its package.
+func trimPackage(s string) string {
+	return strings.TrimPrefix(s, packagePrefix)
+}
+
+// trimPackageLine leaves packagePrefix out of the package named
+// by a package result line, or by the header of its build errors
+// such as "# example.com/foo".
+func trimPackageLine(line, trimmed string) string {
+	var pkg string
+	if _, p, ok := packageResult(trimmed); ok {
+		pkg = p
+	} else if strings.HasPrefix(trimmed, "# ") {
+		pkg = strings.Trim(strings.TrimPrefix(trimmed, "# "), "[]")
+	}
+	if pkg == "" || packagePrefix == "" {
+		return line
+	}
+	return strings.Replace(line, pkg, trimPackage(pkg), 1)
+}
+
+// trimPackages applies trimPackage to every name of names.
+func trimPackages(names []string) []string {
+	trimmed := make([]string, len(names))
+	for i, name := range names {
+		trimmed[i] = trimPackage(name)
+	}
+	return trimmed
+}
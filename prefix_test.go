@@ -0,0 +1,73 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrimPrefix(t *testing.T) {
+	// The main module is example.com/org/repo.
+	script := `case "$1" in
+env) echo /src/repo/go.mod ;;
+list) echo example.com/org/repo ;;
+test) cat <<'EOF'
+--- PASS: TestA (0.00s)
+ok  	example.com/org/repo/internal/foo	0.010s
+--- FAIL: TestB (0.00s)
+FAIL
+FAIL	example.com/org/repo/bar	0.010s
+FAIL
+EOF
+exit 1 ;;
+esac
+`
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"-trim-prefix", "module"},
+			want: []string{"ok  \tinternal/foo\t0.010s\n", "FAIL\tbar\t0.010s\n", "Failures:\n  bar.TestB\n"},
+		},
+		{
+			args: []string{"-trim-prefix", "example.com/org"},
+			want: []string{"ok  \trepo/internal/foo\t0.010s\n", "FAIL\trepo/bar\t0.010s\n", "Failures:\n  repo/bar.TestB\n"},
+		},
+		{
+			// Only -trim-prefix module looks for the main module.
+			args: nil,
+			want: []string{"ok  \texample.com/org/repo/internal/foo\t0.010s\n", "Failures:\n  example.com/org/repo/bar.TestB\n"},
+		},
+	} {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never", "-detail"}, tt.args...)...)
+			defer cleanup()
+			out, err := cmd.Output()
+			if code := exitCodeOf(t, err); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestTrimPrefixNoModule(t *testing.T) {
+	cmd, cleanup := stubGo(t, "echo /dev/null\n", "-trim-prefix", "module")
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := "-trim-prefix module: no go.mod file"; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
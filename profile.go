@@ -0,0 +1,31 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// profile is how gotest spent the run, for -self-profile.
+type profile struct {
+	lines int
+	// read is the time spent waiting for output of go test,
+	// and parse that spent parsing and printing it.
+	read, parse time.Duration
+}
+
+// printProfile reports to stderr whether gotest was waiting
+// for go test or busy with its output.
+func (r *ResultSummary) printProfile() {
+	p := r.profile
+	perLine := time.Duration(0)
+	if p.lines > 0 {
+		perLine = p.parse / time.Duration(p.lines)
+	}
+	fmt.Fprintf(os.Stderr, "gotest: parsed %d lines in %v (%v per line), waited %v for output\n",
+		p.lines, p.parse, perLine, p.read)
+}
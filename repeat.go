@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/fatih/color"
+
+// repeatUntilFailure runs the tests up to max times, stopping
+// at the first run that fails, to catch rare flakes. It returns
+// the summary and exit code of the last run, which is 0 only if
+// every run passed.
+func repeatUntilFailure(args []string, max int) (*ResultSummary, int) {
+	// Cached results would pass every time.
+	*nocache = true
+	summary := &ResultSummary{}
+	for i := 1; i <= max; i++ {
+		color.Cyan("Iteration %d/%d", i, max)
+		var code int
+		if summary, code = test(args); code != 0 {
+			color.Red("Iteration %d failed after %d clean iterations", i, i-1)
+			return summary, code
+		}
+		color.Green("Iteration %d passed", i)
+	}
+	color.Green("All %d iterations passed", max)
+	return summary, 0
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepeatPublishesOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hooks, stepSummary := filepath.Join(dir, "hooks"), filepath.Join(dir, "step-summary.md")
+
+	cmd, cleanup := stubGo(t, "echo 'ok  \texample.com/x\t0.010s'\n", "-color", "never",
+		"-repeat-until-fail", "-repeat-max", "3",
+		"-before", "echo before >> "+hooks, "-after", "echo after >> "+hooks)
+	defer cleanup()
+	cmd.Env = append(cmd.Env, stepSummaryEnv+"="+stepSummary)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "All 3 iterations passed") {
+		t.Errorf("output doesn't report 3 passing iterations:\n%s", out)
+	}
+	b, err := ioutil.ReadFile(hooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "before\nafter\n"; got != want {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", got, want)
+	}
+	b, err = ioutil.ReadFile(stepSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "### "); n != 1 {
+		t.Errorf("step summary has %d runs, want only the last:\n%s", n, b)
+	}
+}
+
+func TestRepeatMaxInvalid(t *testing.T) {
+	cmd, cleanup := stubGo(t, "echo 'ok  \texample.com/x\t0.010s'\n", "-repeat-until-fail", "-repeat-max", "0")
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	for _, want := range []string{"invalid value 0 for flag -repeat-max: must be at least 1\n", "Usage of gotest:"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "iterations passed") {
+		t.Errorf("tests ran with -repeat-max 0:\n%s", out)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// testResult is the outcome of a single test.
+type testResult struct {
+	Package string
+	Name    string
+	Status  string // PASS, FAIL or SKIP
+	Elapsed time.Duration
+	End     time.Time
+	Output  []string // only kept for failed tests
+	Reason  string   // message of skipped tests
+}
+
+// A reporter writes a report of a run once it is over.
+// Any number of reporters can be fed by the same run.
+type reporter interface {
+	report(summary *ResultSummary) error
+}
+
+// reporters returns the reporters enabled by the flags.
+func reporters() []reporter {
+	var reps []reporter
+	if path := reportPath(*failuresFile); path != "" {
+		reps = append(reps, failuresReport{path})
+	}
+	if path := reportPath(*jsonlFailures); path != "" {
+		reps = append(reps, failuresJSONLReport{path})
+	}
+	if path := reportPath(*junitFile); path != "" {
+		reps = append(reps, junitReport{path})
+	}
+	if path := reportPath(*summaryJSON); path != "" {
+		reps = append(reps, jsonReport{path})
+	}
+	if path := reportPath(*htmlFile); path != "" {
+		reps = append(reps, htmlReport{path})
+	}
+	if path := reportPath(*markdownFile); path != "" {
+		reps = append(reps, markdownReport{path: path})
+	}
+	if path := os.Getenv(stepSummaryEnv); path != "" && *stepSummary {
+		reps = append(reps, markdownReport{path: path, append: true})
+	}
+	return reps
+}
+
+// reportPath returns where to write a report given the path
+// set with its flag, or "" if the report isn't enabled. With
+// -output-dir, relative paths are relative to the directory.
+func reportPath(path string) string {
+	if path == "" || *outputDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(*outputDir, path)
+}
+
+// jsonReport writes the summary as JSON to a file.
+type jsonReport struct {
+	path string
+}
+
+type jsonSummary struct {
+	RunID    string        `json:"run_id"`
+	Total    int           `json:"total"`
+	Pass     int           `json:"pass"`
+	Fail     int           `json:"fail"`
+	Skip     int           `json:"skip"`
+	Races    int           `json:"races,omitempty"`
+	Elapsed  float64       `json:"elapsed"`
+	Failures []jsonFailure `json:"failures"`
+}
+
+type jsonFailure struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Elapsed float64 `json:"elapsed"`
+}
+
+func (j jsonReport) report(summary *ResultSummary) error {
+	b, err := json.MarshalIndent(newJSONSummary(summary), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, append(b, '\n'), 0644)
+}
+
+func newJSONSummary(summary *ResultSummary) jsonSummary {
+	c := summary.snapshot()
+	s := jsonSummary{
+		RunID:    runID,
+		Total:    c.total(),
+		Pass:     c.pass,
+		Fail:     c.fail,
+		Skip:     c.skipped,
+		Races:    summary.races,
+		Elapsed:  summary.elapsed.Seconds(),
+		Failures: []jsonFailure{},
+	}
+	for _, t := range summary.tests {
+		if t.Status == "FAIL" {
+			s.Failures = append(s.Failures, jsonFailure{t.Package, t.Name, t.Elapsed.Seconds()})
+		}
+	}
+	return s
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runs := filepath.Join(dir, "runs")
+
+	script := "echo run >> " + runs + "\ncat <<'EOF'\n" +
+		"=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n    x_test.go:9: boom\n--- FAIL: TestB (0.50s)\nFAIL\nFAIL\texample.com/x\t0.510s\n" +
+		"EOF\nexit 1\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v",
+		"-output-dir", dir, "-junit", "junit.xml", "-summary-json", "summary.json")
+	defer cleanup()
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(string(out), "Run ID: ") {
+		t.Errorf("no run ID printed with -summary-json:\n%s", out)
+	}
+	var written []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			written = append(written, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(written)
+	if got, want := strings.Join(written, " "), "junit.xml runs summary.json"; got != want {
+		t.Errorf("wrote %s, want %s", got, want)
+	}
+	// Both reports are of a single run of go test.
+	if b, err := ioutil.ReadFile(runs); err != nil || string(b) != "run\n" {
+		t.Errorf("go test ran %q times, want once", b)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "junit.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(b, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("junit.xml has %d suites, want 1:\n%s", len(suites.Suites), b)
+	}
+	s := suites.Suites[0]
+	if s.Name != "example.com/x" || s.Tests != 2 || s.Failures != 1 || len(s.Cases) != 2 {
+		t.Errorf("junit.xml suite %s has %d tests, %d failures, want example.com/x with 2 tests, 1 failure:\n%s", s.Name, s.Tests, s.Failures, b)
+	} else if c := s.Cases[1]; c.Name != "TestB" || c.Failure == nil || !strings.Contains(c.Failure.Contents, "x_test.go:9: boom") {
+		t.Errorf("junit.xml doesn't report TestB failing with its output:\n%s", b)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary jsonSummary
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+	want := []jsonFailure{{Package: "example.com/x", Test: "TestB", Elapsed: 0.5}}
+	if summary.RunID == "" || summary.Pass != 1 || !reflect.DeepEqual(summary.Failures, want) {
+		t.Errorf("summary.json doesn't report TestA passing and TestB failing in a run:\n%s", b)
+	}
+}
+
+func TestFailuresFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failures.txt")
+
+	err = gotestCmd("-color", "never", "-dry-parse", filepath.Join("testdata", "fail.txt"), "-failures-file", path).Run()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TestA passed, so none of its output is kept.
+	want := "=== RUN   TestB\n    example_test.go:18: failed\n--- FAIL: TestB (0.00s)\n" +
+		"\n=== RUN   TestC\n=== PAUSE TestC\n=== CONT  TestC\n    example_test.go:24: failed\n--- FAIL: TestC (1.00s)\n" +
+		"\n=== RUN   TestD\n=== PAUSE TestD\n=== CONT  TestD\n    example_test.go:29: failed\n--- FAIL: TestD (0.00s)\n"
+	if got := string(b); got != want {
+		t.Errorf("-failures-file wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestJSONLFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failures.jsonl")
+
+	err = gotestCmd("-color", "never", "-run-id", "ci-42", "-dry-parse", filepath.Join("testdata", "fail.jsonl"), "-jsonl-failures", path).Run()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []jsonlFailure
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(string(b), "\n"), "\n") {
+		var f jsonlFailure
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			t.Fatalf("-jsonl-failures line %q: %v", line, err)
+		}
+		got = append(got, f)
+	}
+	// TestA passed, so there is no line for it.
+	pkg := "github.com/rakyll/gotest/example"
+	want := []jsonlFailure{
+		{"ci-42", pkg, "TestB", 0, []string{"=== RUN   TestB", "    example_test.go:18: failed", "--- FAIL: TestB (0.00s)"}},
+		{"ci-42", pkg, "TestC", 1, []string{"=== RUN   TestC", "=== PAUSE TestC", "=== CONT  TestC", "    example_test.go:24: failed", "--- FAIL: TestC (1.00s)"}},
+		{"ci-42", pkg, "TestD", 0, []string{"=== RUN   TestD", "=== PAUSE TestD", "=== CONT  TestD", "    example_test.go:29: failed", "--- FAIL: TestD (0.00s)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("-jsonl-failures wrote %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryMarkdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tt := range []struct {
+		fixture string
+		code    int
+		want    []string
+		notWant string
+	}{
+		{
+			fixture: "fail.txt",
+			code:    1,
+			want: []string{
+				"### :x: Tests failed\n",
+				"| Total | Passed | Skipped | Failed | Elapsed |\n",
+				"\n| 7 | 1 | 0 | 6 | ",
+				"<details>\n<summary>3 failed tests</summary>\n",
+				"#### `github.com/rakyll/gotest/example.TestB`\n\n```\n=== RUN   TestB\n    example_test.go:18: failed\n--- FAIL: TestB (0.00s)\n```\n",
+				"#### `github.com/rakyll/gotest/example.TestC`\n",
+				"#### `github.com/rakyll/gotest/example.TestD`\n",
+				"</details>\n",
+			},
+		},
+		{
+			fixture: "skip.txt",
+			want:    []string{"### :white_check_mark: Tests passed\n", "\n| 8 | 3 | 5 | 0 | "},
+			notWant: "<details>",
+		},
+	} {
+		path := filepath.Join(dir, tt.fixture+".md")
+		err := gotestCmd("-color", "never", "-dry-parse", filepath.Join("testdata", tt.fixture), "-summary-markdown", path).Run()
+		if code := exitCodeOf(t, err); code != tt.code {
+			t.Errorf("%s: exit code = %d, want %d", tt.fixture, code, tt.code)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(b), want) {
+				t.Errorf("%s: -summary-markdown lacks %q:\n%s", tt.fixture, want, b)
+			}
+		}
+		if tt.notWant != "" && strings.Contains(string(b), tt.notWant) {
+			t.Errorf("%s: -summary-markdown has %q:\n%s", tt.fixture, tt.notWant, b)
+		}
+	}
+}
+
+func TestStepSummary(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("## Earlier step\n")
+	f.Close()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := gotestCmd(append([]string{"-color", "never", "-dry-parse", filepath.Join("testdata", "skip.txt")}, args...)...)
+		cmd.Env = append(cmd.Env, stepSummaryEnv+"="+f.Name())
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each run appends its summary to what is already there.
+	run()
+	run()
+	run("-step-summary=false")
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "## Earlier step\n### :white_check_mark: Tests passed\n") {
+		t.Errorf("$%s doesn't start with the earlier content and the summary:\n%s", stepSummaryEnv, b)
+	}
+	if n := strings.Count(string(b), "Tests passed"); n != 2 {
+		t.Errorf("$%s has %d summaries, want 2:\n%s", stepSummaryEnv, n, b)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// verboseArgs returns the go test args to test only pkg,
+// verbosely, with the same flags as args.
+func verboseArgs(args []string, pkg string) []string {
+	flagArgs, _, testArgs := splitArgs(args)
+	out := append([]string{}, flagArgs...)
+	out = append(out, "-v", pkg)
+	return append(out, testArgs...)
+}
+
+// rerunVerbose tests the first package that failed again with
+// -v, unless the run was verbose already, after the summary of
+// the run. Its results are only shown, not counted in summary.
+func rerunVerbose(args []string, summary *ResultSummary) {
+	if len(summary.failedPackages) == 0 || hasJSONFlag(args) {
+		return
+	}
+	flagArgs, _, _ := splitArgs(args)
+	if v, ok := flagValue(flagArgs, "v"); ok && v != "false" {
+		return
+	}
+	pkg := summary.failedPackages[0]
+	color.New(color.FgCyan).Printf("Rerunning %s with -v:\n", pkg)
+	rerun := &ResultSummary{}
+	run(verboseArgs(args, pkg), rerun)
+	rerun.flush()
+}
+
+// rerunCommands returns, for each package with failed tests,
+// the go test command running only those tests. Subtests are
+// run through their top-level test, and known flaky tests are
+// left out.
+func (r *ResultSummary) rerunCommands() []string {
+	var pkgs []string
+	failed := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, t := range r.tests {
+		if t.Status != "FAIL" || isFlaky(t.Name) {
+			continue
+		}
+		name := t.Name
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i]
+		}
+		if seen[t.Package+" "+name] {
+			continue
+		}
+		seen[t.Package+" "+name] = true
+		if _, ok := failed[t.Package]; !ok {
+			pkgs = append(pkgs, t.Package)
+		}
+		failed[t.Package] = append(failed[t.Package], name)
+	}
+	cmds := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		args := []string{"go", "test", "-run", "^(" + strings.Join(failed[pkg], "|") + ")$"}
+		if pkg != "" {
+			args = append(args, pkg)
+		}
+		cmds[i] = commandLine(args)
+	}
+	return cmds
+}
+
+// printRerunCommands prints the commands to test only the
+// failed tests again, ready to copy.
+func (r *ResultSummary) printRerunCommands() {
+	cmds := r.rerunCommands()
+	if len(cmds) == 0 {
+		return
+	}
+	color.Cyan("Rerun failed tests with:")
+	for _, cmd := range cmds {
+		color.White("  %s", cmd)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerboseOnFail(t *testing.T) {
+	// Only the rerun is verbose, and shows the log of TestB.
+	script := "case \"$*\" in\n" +
+		"*-v*) cat <<'EOF'\n=== RUN   TestB\n    x_test.go:7: connecting\n--- FAIL: TestB (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\nEOF\n;;\n" +
+		"*) cat <<'EOF'\n--- FAIL: TestB (0.00s)\nFAIL\nFAIL\texample.com/x\t0.010s\nEOF\n;;\nesac\nexit 1\n"
+	for _, args := range [][]string{nil, {"-sort-output"}, {"-failures-last"}} {
+		t.Run(fmt.Sprint(args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never", "-verbose-on-fail"}, args...)...)
+			defer cleanup()
+			out, err := cmd.Output()
+			if code := exitCodeOf(t, err); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			// The rerun follows the summary, which only counts the run.
+			summary := strings.Index(string(out), "\nFAIL: 3\n")
+			rerun := strings.Index(string(out), "Rerunning example.com/x with -v:\n")
+			log := strings.Index(string(out), "    x_test.go:7: connecting\n")
+			if summary < 0 || rerun < summary || log < rerun {
+				t.Errorf("output lacks the summary, then the rerun with the log of TestB:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRerunCommands(t *testing.T) {
+	defer func(old map[string]bool) { flakyTests = old }(flakyTests)
+	flakyTests = map[string]bool{"TestFlaky": true}
+	r := &ResultSummary{tests: []testResult{
+		{Package: "example.com/a", Name: "TestA", Status: "FAIL"},
+		{Package: "example.com/b", Name: "TestPass", Status: "PASS"},
+		{Package: "example.com/b", Name: "TestB/one", Status: "FAIL"},
+		{Package: "example.com/b", Name: "TestB/two", Status: "FAIL"},
+		{Package: "example.com/b", Name: "TestB", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestC", Status: "FAIL"},
+		{Package: "example.com/a", Name: "TestFlaky", Status: "FAIL"},
+		{Package: "example.com/c", Name: "TestSkip", Status: "SKIP"},
+	}}
+	// Subtests rerun through their top-level test, once.
+	want := []string{
+		"go test -run '^(TestA|TestC)$' example.com/a",
+		"go test -run '^(TestB)$' example.com/b",
+	}
+	if got := r.rerunCommands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("rerunCommands() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// A rule hides or colors the lines matching its pattern.
+type rule struct {
+	re   *regexp.Regexp
+	hide bool
+	c    color.Attribute
+}
+
+// rules are the rules loaded from -rules, in the order they
+// were listed.
+var rules []rule
+
+// loadRules reads the -rules file, which lists one rule per
+// line, either
+//
+//	hide <regexp>
+//	color <color> <regexp>
+//
+// where <color> is one of the -pass-color colors or pass,
+// fail, skip or neutral for the colors in use. Blank lines and
+// lines starting with # are ignored.
+func loadRules() error {
+	if *rulesFile == "" {
+		return nil
+	}
+	f, err := os.Open(*rulesFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRule(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", *rulesFile, n, err)
+		}
+		rules = append(rules, r)
+	}
+	return s.Err()
+}
+
+// parseRule parses a line of a rules file.
+func parseRule(line string) (rule, error) {
+	var r rule
+	action, pattern := cutField(line)
+	switch action {
+	case "hide":
+		r.hide = true
+	case "color":
+		var name string
+		name, pattern = cutField(pattern)
+		c, ok := ruleColor(name)
+		if !ok {
+			return r, fmt.Errorf("unknown color %q", name)
+		}
+		r.c = c
+	default:
+		return r, fmt.Errorf("unknown action %q: must be hide or color", action)
+	}
+	if pattern == "" {
+		return r, fmt.Errorf("missing pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return r, err
+	}
+	r.re = re
+	return r, nil
+}
+
+// cutField splits the first whitespace-separated field off s.
+func cutField(s string) (field, rest string) {
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// ruleColor returns the color a rule names.
+func ruleColor(name string) (color.Attribute, bool) {
+	switch name {
+	case "pass":
+		return pass, true
+	case "fail":
+		return fail, true
+	case "skip":
+		return skip, true
+	case "neutral":
+		return neutral, true
+	}
+	c, ok := colors[name]
+	return c, ok
+}
+
+// matchRule returns the first rule matching a trimmed line.
+func matchRule(trimmed string) (rule, bool) {
+	for _, r := range rules {
+		if r.re.MatchString(trimmed) {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseRule(t *testing.T) {
+	for _, tt := range []struct {
+		line    string
+		pattern string
+		hide    bool
+		c       color.Attribute
+		err     string
+	}{
+		{line: `hide ^=== (PAUSE|CONT)`, pattern: `^=== (PAUSE|CONT)`, hide: true},
+		{line: "color magenta\t_test\\.go:\\d+:", pattern: `_test\.go:\d+:`, c: color.FgMagenta},
+		{line: `color skip ^--- FAIL: TestKnownBad`, pattern: `^--- FAIL: TestKnownBad`, c: skip},
+		{line: `color orange x`, err: `unknown color "orange"`},
+		{line: `dim x`, err: `unknown action "dim": must be hide or color`},
+		{line: `hide`, err: "missing pattern"},
+		{line: `color fail`, err: "missing pattern"},
+		{line: `hide (`, err: "missing closing )"},
+	} {
+		r, err := parseRule(tt.line)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseRule(%q) error = %v, want %q", tt.line, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRule(%q) error = %v", tt.line, err)
+			continue
+		}
+		if r.re.String() != tt.pattern || r.hide != tt.hide || r.c != tt.c {
+			t.Errorf("parseRule(%q) = %q, hide %v, color %v, want %q, hide %v, color %v", tt.line, r.re, r.hide, r.c, tt.pattern, tt.hide, tt.c)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rulesFile := filepath.Join(dir, "rules.txt")
+	rules := "# rules.txt\n\nhide example_test\\.go:24:\ncolor magenta _test\\.go:\\d+:\ncolor skip ^--- FAIL: TestD\n"
+	if err := ioutil.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := gotestCmd("-color", "always", "-rules", rulesFile, "-dry-parse", filepath.Join("testdata", "fail.txt")).Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	colored := func(c color.Attribute, line string) string {
+		return fmt.Sprintf("\033[%dm%s\n", c, line)
+	}
+	// The first matching rule applies.
+	for _, want := range []string{
+		colored(color.FgMagenta, "    example_test.go:18: failed"),
+		colored(color.FgMagenta, "    example_test.go:29: failed"),
+		colored(skip, "--- FAIL: TestD (0.00s)"),
+		colored(fail, "--- FAIL: TestC (1.00s)"),
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%q", want, out)
+		}
+	}
+	if strings.Contains(string(out), "example_test.go:24") {
+		t.Errorf("output has the hidden line:\n%q", out)
+	}
+	// Rules don't change the counts.
+	if want := "FAIL: 6"; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%q", want, out)
+	}
+}
+
+func TestRulesInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("hide ^ok\n# dimmed\ndim ^PASS\n")
+	f.Close()
+	out, err := gotestCmd("-rules", f.Name(), "-dry-parse", filepath.Join("testdata", "skip.txt")).CombinedOutput()
+	if code := exitCodeOf(t, err); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	if want := f.Name() + `:3: unknown action "dim"`; !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// runID identifies the run in the events and the JSON summary,
+// so that dashboards can group everything from one invocation.
+var runID string
+
+// loadRunID sets runID to -run-id, or to a random UUID if
+// it isn't set.
+func loadRunID() error {
+	if *runIDFlag != "" {
+		runID = *runIDFlag
+		return nil
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	runID = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	return nil
+}
+
+// printRunID prints the run ID before the output, if it was
+// set or there is structured output to find it in.
+func printRunID() {
+	if *runIDFlag != "" || events != nil || *webhookURL != "" || *summaryJSON != "" ||
+		*jsonlFailures != "" || *otlpEndpoint != "" {
+		color.New(neutral).Println("Run ID: " + runID)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestLoadRunID(t *testing.T) {
+	defer func(id string) { runID = id }(runID)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if err := loadRunID(); err != nil {
+		t.Fatal(err)
+	}
+	first := runID
+	if !uuid.MatchString(first) {
+		t.Errorf("run ID %q is not a random UUID", first)
+	}
+	if err := loadRunID(); err != nil {
+		t.Fatal(err)
+	}
+	if runID == first {
+		t.Errorf("two runs got the same ID %q", runID)
+	}
+}
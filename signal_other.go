@@ -0,0 +1,22 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// sigpipe has SIGPIPE's usual number, so that a broken pipe
+// exits as it does elsewhere, as not every platform defines it.
+const sigpipe = syscall.Signal(13)
+
+// teardownSignals are the signals that stop gotest without
+// running teardown unless it catches them. There is no SIGHUP
+// on this platform.
+var teardownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
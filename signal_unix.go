@@ -0,0 +1,20 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// sigpipe is the signal a write to a pipe with no readers raises.
+const sigpipe = syscall.SIGPIPE
+
+// teardownSignals are the signals that stop gotest without
+// running teardown unless it catches them.
+var teardownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
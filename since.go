@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitChangedFiles returns the files that changed relative to ref,
+// and the new files git doesn't track yet, relative to the current
+// directory.
+func gitChangedFiles(ref string) ([]string, error) {
+	changed, err := git("diff", "--name-only", "--relative", ref)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return append(changed, untracked...), nil
+}
+
+// git runs git with args and returns the fields of its output.
+func git(args ...string) ([]string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		cmdline := "git " + strings.Join(args, " ")
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", cmdline, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %v", cmdline, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// changedPackages maps changed files to the packages that contain
+// them, as relative import paths such as "./foo/bar". Packages
+// whose directory no longer exists are left out.
+func changedPackages(files []string) []string {
+	seen := make(map[string]bool)
+	var pkgs []string
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") {
+			continue
+		}
+		dir := filepath.Dir(f)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if dir == "." {
+			pkgs = append(pkgs, ".")
+			continue
+		}
+		pkgs = append(pkgs, "./"+filepath.ToSlash(dir))
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// withPackages adds pkgs to the go test args, ahead of
+// any arguments meant for the test binary.
+func withPackages(args, pkgs []string) []string {
+	for i, arg := range args {
+		if arg == "-args" || arg == "--args" {
+			out := append([]string{}, args[:i]...)
+			out = append(out, pkgs...)
+			return append(out, args[i:]...)
+		}
+	}
+	return append(args, pkgs...)
+}
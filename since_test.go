@@ -0,0 +1,88 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// stubGit puts a git command running script first in PATH
+// until the returned function is called.
+func stubGit(t *testing.T, script string) func() {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub git command is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "git"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	defer stubGit(t, `case "$1" in
+diff) echo "a/a.go"; echo "README.md" ;;
+ls-files) echo "b/new.go" ;;
+esac
+`)()
+	files, err := gitChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a/a.go", "README.md", "b/new.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("gitChangedFiles = %q, want %q", files, want)
+	}
+}
+
+func TestGitChangedFilesError(t *testing.T) {
+	defer stubGit(t, "echo \"fatal: bad revision 'nope'\" >&2; exit 128\n")()
+	_, err := gitChangedFiles("nope")
+	if err == nil || err.Error() != "git diff --name-only --relative nope: fatal: bad revision 'nope'" {
+		t.Errorf("gitChangedFiles error = %v", err)
+	}
+}
+
+func TestChangedPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a", "b/c"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{"b/c/new.go", "a/a.go", "a/a_test.go", "README.md", "main.go", "gone/gone.go"}
+	want := []string{".", "./a", "./b/c"}
+	if pkgs := changedPackages(files); !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("changedPackages = %q, want %q", pkgs, want)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// logLine matches a trimmed line logged by a test, such as
+// "foo_test.go:5: requires network".
+var logLine = regexp.MustCompile(`^\S+\.go:\d+: (.*)$`)
+
+// noReason is the reason reported for tests skipped
+// without a message, as with t.SkipNow.
+const noReason = "no reason given"
+
+// testLog is a line logged by a test, held back with
+// -skip-reasons until the next line shows whether it
+// was the reason the test was skipped.
+type testLog struct {
+	test          string
+	line, trimmed string
+	c             color.Attribute
+}
+
+// holdLog holds back line if it was logged by a test.
+// The message of t.Skip is logged right before go test
+// prints "--- SKIP", and is only known to be the reason
+// for the skip once that line is parsed. It reports
+// whether line was held.
+func (r *ResultSummary) holdLog(line, trimmed string, c color.Attribute) bool {
+	if *countsOnly || r.current == "" || !logLine.MatchString(trimmed) {
+		return false
+	}
+	r.lastLog = &testLog{r.current, line, trimmed, c}
+	return true
+}
+
+// skipReason prints the log line held back before trimmed,
+// in the skip color if trimmed is the "--- SKIP" line of the
+// test that logged it, which makes it the reason for the skip.
+func (r *ResultSummary) skipReason(trimmed string) {
+	l := r.lastLog
+	r.lastLog = nil
+	if l == nil {
+		return
+	}
+	if strings.HasPrefix(trimmed, "--- SKIP:") && l.test == testName(trimmed) && l.c != fail {
+		l.c = skip
+	}
+	if *accessible {
+		l.c = 0
+	}
+	r.emit(l.line, l.trimmed, l.c)
+}
+
+// skipMessage returns the message a test was skipped with,
+// the last line it logged, given its output up to its result.
+func skipMessage(status string, output []string) string {
+	if status != "SKIP" {
+		return ""
+	}
+	for i := len(output) - 1; i >= 0; i-- {
+		if m := logLine.FindStringSubmatch(strings.TrimSpace(output[i])); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// printSkipReasons prints the number of tests skipped
+// for each reason, most common first.
+func (r *ResultSummary) printSkipReasons() {
+	counts := make(map[string]int)
+	var reasons []string
+	for _, t := range r.tests {
+		if t.Status != "SKIP" {
+			continue
+		}
+		reason := t.Reason
+		if reason == "" {
+			reason = noReason
+		}
+		if counts[reason] == 0 {
+			reasons = append(reasons, reason)
+		}
+		counts[reason]++
+	}
+	if len(reasons) == 0 {
+		return
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	color.Cyan("Skip reasons:")
+	for _, reason := range reasons {
+		color.Yellow("  %d skipped: %s", counts[reason], reason)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/fatih/color"
+
+// printSnapshot prints the counts so far between two lines
+// of output, without stopping the run.
+func (r *ResultSummary) printSnapshot() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	color.New(neutral).Fprintf(color.Output, "So far: %d passed, %d skipped, %d failed\n", r.pass, r.skipped, r.fail)
+}
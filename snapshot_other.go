@@ -0,0 +1,22 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import "os"
+
+// watchSnapshots does nothing, as there is no SIGUSR1 on
+// this platform.
+func watchSnapshots(r *ResultSummary) func() {
+	return func() {}
+}
+
+// isSnapshotSignal reports false, as there is no SIGUSR1 on
+// this platform.
+func isSnapshotSignal(sig os.Signal) bool {
+	return false
+}
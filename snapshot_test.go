@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// TestSnapshotWhileParsing reads the counts the way the snapshot
+// watcher and the title do, while the output is being parsed.
+// Run it with -race.
+func TestSnapshotWhileParsing(t *testing.T) {
+	out := color.Output
+	defer func() { color.Output = out }()
+	color.Output = ioutil.Discard
+
+	const n = 200
+	summary := &ResultSummary{}
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				summary.printSnapshot()
+			}
+		}
+	}()
+	go func() {
+		defer readers.Done()
+		ti := newTitle(ioutil.Discard, true)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if c := summary.snapshot(); c.total() > n {
+					t.Errorf("counted %d tests, want at most %d", c.total(), n)
+				}
+				ti.update(summary)
+			}
+		}
+	}()
+
+	pr, pw := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go consume(&wg, pr, summary, parse)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(pw, "--- PASS: Test%d (0.00s)\n", i)
+	}
+	pw.Close()
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if c := summary.snapshot(); c.pass != n {
+		t.Errorf("pass = %d, want %d", c.pass, n)
+	}
+}
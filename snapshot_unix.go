@@ -0,0 +1,43 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchSnapshots prints the counts of r so far whenever gotest
+// receives SIGUSR1, until the returned function is called.
+func watchSnapshots(r *ResultSummary) func() {
+	sigc := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigc, syscall.SIGUSR1)
+
+	go func() {
+		for {
+			select {
+			case <-sigc:
+				r.printSnapshot()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigc)
+		done <- struct{}{}
+	}
+}
+
+// isSnapshotSignal reports whether sig asks for the counts so
+// far, rather than being meant for go test.
+func isSnapshotSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR1
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSnapshotSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pid, done := filepath.Join(dir, "pid"), filepath.Join(dir, "done")
+	script := "printf -- '--- PASS: TestA (0.00s)\\n--- PASS: TestB (0.00s)\\n--- FAIL: TestC (0.00s)\\n'\n" +
+		"echo $$ > " + pid + "\n" +
+		"while [ ! -f " + done + " ]; do sleep 0.1; done\n" +
+		"printf 'FAIL\\nFAIL\\texample.com/x\\t0.010s\\n'\nexit 1\n"
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-v")
+	defer cleanup()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer killStub(pid)
+	lines := make(chan string)
+	go func() {
+		s := bufio.NewScanner(stdout)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}()
+	waitForStub(t, pid)
+
+	// go test keeps running, and isn't sent the signal.
+	cmd.Process.Signal(syscall.SIGUSR1)
+	timeout := time.After(10 * time.Second)
+wait:
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("gotest exited on SIGUSR1")
+			}
+			if line == "So far: 2 passed, 0 skipped, 1 failed" {
+				break wait
+			}
+		case <-timeout:
+			t.Fatal("no counts printed on SIGUSR1")
+		}
+	}
+	if err := ioutil.WriteFile(done, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for range lines {
+	}
+	if code := exitCodeOf(t, cmd.Wait()); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// sortedLine is a line of output held back by -sort-output,
+// along with the package and test it belongs to.
+type sortedLine struct {
+	pkg, test string
+	line      string
+	c         color.Attribute
+}
+
+// buffer holds back line, to be printed in color c once the
+// run is over. Without -json, the package of a line is only
+// known once the package is over, which endPackage records.
+func (r *ResultSummary) buffer(line, trimmed string, c color.Attribute) {
+	pkg := r.pkg
+	if _, p, ok := packageResult(trimmed); ok {
+		pkg = p
+	}
+	r.sorted = append(r.sorted, sortedLine{pkg, r.current, line, c})
+}
+
+// printSorted prints the output held back by -sort-output
+// sorted by package and then by test, each test keeping the
+// order of its own output. The lines of a package that belong
+// to no test follow its tests, and lines that belong to no
+// package follow all packages.
+func (r *ResultSummary) printSorted() {
+	sort.SliceStable(r.sorted, func(i, j int) bool {
+		a, b := r.sorted[i], r.sorted[j]
+		if a.pkg != b.pkg {
+			return b.pkg == "" || a.pkg != "" && a.pkg < b.pkg
+		}
+		if a.test != b.test {
+			return b.test == "" || a.test != "" && a.test < b.test
+		}
+		return false
+	})
+	for _, l := range r.sorted {
+		r.println(l.line, l.c)
+	}
+	r.sorted = nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSortOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := func(pkg, test, out string) string {
+		if test == "" {
+			return fmt.Sprintf(`{"Action":"output","Package":%q,"Output":%q}`+"\n", pkg, out)
+		}
+		return fmt.Sprintf(`{"Action":"output","Package":%q,"Test":%q,"Output":%q}`+"\n", pkg, test, out)
+	}
+	// The output of each test and package, in order.
+	sequences := func() map[string][]string {
+		return map[string][]string{
+			"a.TestY": {
+				output("example.com/a", "TestY", "=== RUN   TestY\n"),
+				output("example.com/a", "TestY", "    a_test.go:9: y\n"),
+				output("example.com/a", "TestY", "--- PASS: TestY (0.00s)\n"),
+			},
+			"a.TestX": {
+				output("example.com/a", "TestX", "=== RUN   TestX\n"),
+				output("example.com/a", "TestX", "    a_test.go:5: x\n"),
+				output("example.com/a", "TestX", "--- FAIL: TestX (0.00s)\n"),
+			},
+			"a": {
+				output("example.com/a", "", "FAIL\n"),
+				output("example.com/a", "", "FAIL\texample.com/a\t0.010s\n"),
+			},
+			"b.TestZ": {
+				output("example.com/b", "TestZ", "=== RUN   TestZ\n"),
+				output("example.com/b", "TestZ", "--- PASS: TestZ (0.00s)\n"),
+			},
+			"b": {
+				output("example.com/b", "", "PASS\n"),
+				output("example.com/b", "", "ok  \texample.com/b\t0.010s\n"),
+			},
+		}
+	}
+	// interleave takes the next line of each of the tests, and
+	// then of each of the packages, in the given order in turn.
+	interleave := func(tests, pkgs []string) string {
+		seqs := sequences()
+		var b strings.Builder
+		for _, order := range [][]string{tests, pkgs} {
+			for left := true; left; {
+				left = false
+				for _, k := range order {
+					if len(seqs[k]) > 0 {
+						b.WriteString(seqs[k][0])
+						seqs[k] = seqs[k][1:]
+						left = true
+					}
+				}
+			}
+		}
+		return b.String()
+	}
+	streams := []string{
+		interleave([]string{"b.TestZ", "a.TestY", "a.TestX"}, []string{"b", "a"}),
+		interleave([]string{"a.TestX", "a.TestY", "b.TestZ"}, []string{"a", "b"}),
+	}
+
+	want := "    a_test.go:5: x\n--- FAIL: TestX (0.00s)\n" +
+		"    a_test.go:9: y\n--- PASS: TestY (0.00s)\n" +
+		"FAIL\nFAIL\texample.com/a\t0.010s\n" +
+		"--- PASS: TestZ (0.00s)\n" +
+		"PASS\nok  \texample.com/b\t0.010s\n" +
+		"Summary:\n"
+	for i, stream := range streams {
+		path := filepath.Join(dir, fmt.Sprintf("stream%d.jsonl", i))
+		if err := ioutil.WriteFile(path, []byte(stream), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := gotestCmd("-color", "never", "-sort-output", "-dry-parse", path).Output()
+		if code := exitCodeOf(t, err); code != 1 {
+			t.Errorf("stream %d: exit code = %d, want 1", i, code)
+		}
+		if !strings.HasPrefix(string(out), want) {
+			t.Errorf("stream %d: output doesn't start with:\n%s\ngot:\n%s", i, want, out)
+		}
+	}
+}
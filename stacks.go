@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// goroutineHeader starts the stack of a goroutine, as in
+	// "goroutine 6 [running]:".
+	goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	// stackCall is the call of a stack frame, as in
+	// "main.f(0x1, ...)", followed by its location.
+	stackCall = regexp.MustCompile(`^[^\s(][^\s]*\(.*\)$`)
+)
+
+// stack counts the frames of the goroutine stack being parsed.
+type stack struct {
+	frames int
+	hidden int
+}
+
+// collapseStack reports whether line is a frame of a goroutine
+// stack past the first -collapse-stacks frames, which are left
+// out. Once the stack is over, the number of frames left out is
+// printed in their place.
+func (r *ResultSummary) collapseStack(line, trimmed string) bool {
+	if *collapseStacks <= 0 {
+		return false
+	}
+	if goroutineHeader.MatchString(trimmed) {
+		r.endStack()
+		r.stack = &stack{}
+		return false
+	}
+	s := r.stack
+	if s == nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(line, "\t") && trimmed != "":
+		// The location of the call above.
+		return s.frames > *collapseStacks
+	case stackCall.MatchString(trimmed) || strings.HasPrefix(trimmed, "created by "):
+		s.frames++
+		if s.frames > *collapseStacks {
+			s.hidden++
+			return true
+		}
+		return false
+	}
+	r.endStack()
+	return false
+}
+
+// endStack notes how many frames of the stack were left out.
+func (r *ResultSummary) endStack() {
+	s := r.stack
+	r.stack = nil
+	if s == nil || s.hidden == 0 || *countsOnly {
+		return
+	}
+	note := fmt.Sprintf("\t... (%d more frames)", s.hidden)
+	r.emit(note, strings.TrimSpace(note), neutral)
+}
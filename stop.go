@@ -0,0 +1,20 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "os"
+
+// stopAfter stops go test once pkg has failed, for
+// -stop-on-package-fail. The output of pkg is complete by
+// then, and only the packages still being tested are cut short.
+func (r *ResultSummary) stopAfter(pkg string) {
+	if !*stopOnPackageFail || r.stoppedBy != "" {
+		return
+	}
+	r.stoppedBy = pkg
+	if r.signalChild != nil {
+		r.signalChild(os.Interrupt)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStopOnPackageFail(t *testing.T) {
+	// go test goes on to test example.com/b after example.com/a
+	// failed, for up to 10s unless it is interrupted.
+	script := `trap 'echo "go test got SIGINT"; exit 1' INT
+printf -- '--- FAIL: TestA (0.00s)\nFAIL\nFAIL\texample.com/a\t0.010s\n'
+i=0
+while [ $i -lt 100 ]; do sleep 0.1; i=$((i+1)); done
+printf 'ok  \texample.com/b\t10.000s\n'
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-stop-on-package-fail")
+	defer cleanup()
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	for _, want := range []string{"FAIL\texample.com/a\t0.010s\n", "go test got SIGINT\n", "Stopped after example.com/a failed\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "example.com/b") {
+		t.Errorf("go test wasn't stopped before testing example.com/b:\n%s", out)
+	}
+}
+
+func TestStopOnPackageFailPassing(t *testing.T) {
+	script := `trap 'echo "go test got SIGINT"; exit 1' INT
+printf -- '--- PASS: TestA (0.00s)\nok  \texample.com/a\t0.010s\n'
+sleep 0.2
+printf -- '--- PASS: TestB (0.00s)\nok  \texample.com/b\t0.010s\n'
+`
+	cmd, cleanup := stubGo(t, script, "-color", "never", "-stop-on-package-fail")
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, out)
+	}
+	for _, notWant := range []string{"go test got SIGINT", "Stopped after"} {
+		if strings.Contains(string(out), notWant) {
+			t.Errorf("output has %q although no package failed:\n%s", notWant, out)
+		}
+	}
+}
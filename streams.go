@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// stderrMark starts the lines go test writes to stderr once
+// the streams are split, so they can still be told apart after
+// being read from the same pipe as stdout. consume strips it.
+const stderrMark = "\x00stderr\x00"
+
+// stderrTag is printed before lines from stderr with -tag-stderr.
+const stderrTag = "[stderr] "
+
+// lineWriter writes whole lines to w, starting with mark, so
+// that lines of stdout and stderr don't mix. tee, if set, is
+// written the lines as they are.
+type lineWriter struct {
+	mu   *sync.Mutex
+	w    io.Writer
+	tee  io.Writer
+	mark string
+	buf  []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := l.writeLine(l.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		l.buf = l.buf[i+1:]
+	}
+}
+
+func (l *lineWriter) writeLine(line []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tee != nil {
+		l.tee.Write(line)
+	}
+	_, err := io.WriteString(l.w, l.mark+string(line))
+	return err
+}
+
+// flush writes the last line, if it didn't end in a newline.
+func (l *lineWriter) flush() {
+	if len(l.buf) > 0 {
+		l.writeLine(append(l.buf, '\n'))
+		l.buf = nil
+	}
+}
+
+// splitStreams writes the stdout and stderr of cmd to w line by
+// line, marking the lines of stderr. The returned function
+// writes what is left once cmd is done.
+func splitStreams(cmd *exec.Cmd, w, tee io.Writer) (flush func()) {
+	var mu sync.Mutex
+	stdout := &lineWriter{mu: &mu, w: w, tee: tee}
+	stderr := &lineWriter{mu: &mu, w: w, tee: tee, mark: stderrMark}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// holdStderr holds back a line from stderr for -separate-streams
+// until the package being tested is over, so it follows the
+// output on stdout. It reports whether line was held.
+func (r *ResultSummary) holdStderr(line string, c color.Attribute) bool {
+	if !*separateStreams || !r.stderr {
+		return false
+	}
+	r.heldStderr = append(r.heldStderr, heldLine{line, c})
+	return true
+}
+
+// releaseStderr prints the lines from stderr held back by
+// -separate-streams.
+func (r *ResultSummary) releaseStderr() {
+	held := r.heldStderr
+	r.heldStderr, r.stderr = nil, false
+	for _, l := range held {
+		r.emit(l.line, strings.TrimSpace(l.line), l.c)
+	}
+}
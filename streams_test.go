@@ -0,0 +1,84 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTagStderr(t *testing.T) {
+	// A test writes to fd 2 directly, in between its output.
+	script := `echo '=== RUN   TestA'
+echo '    a_test.go:5: on stdout'
+echo 'from fd 2' >&2
+echo '--- PASS: TestA (0.00s)'
+printf 'ok  \texample.com/a\t0.010s\n'
+`
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "\nfrom fd 2\n"},
+		{args: []string{"-tag-stderr"}, want: "\n" + stderrTag + "from fd 2\n"},
+	} {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never", "-v"}, tt.args...)...)
+			defer cleanup()
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The streams are read apart, so the line from stderr
+			// can come anywhere, but only it is tagged.
+			lines := "\n" + string(out)
+			for _, want := range []string{tt.want, "\n    a_test.go:5: on stdout\n", "\n--- PASS: TestA (0.00s)\n"} {
+				if !strings.Contains(lines, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+			if strings.Contains(string(out), stderrMark) {
+				t.Errorf("output has the stderr mark:\n%q", out)
+			}
+		})
+	}
+}
+
+func TestSeparateStreams(t *testing.T) {
+	// The sleep lets the line from stderr be read before the
+	// rest of the output of example.com/a.
+	script := `echo '=== RUN   TestA'
+echo 'from fd 2' >&2
+sleep 0.2
+echo '--- PASS: TestA (0.00s)'
+printf 'ok  \texample.com/a\t0.010s\n'
+echo '=== RUN   TestB'
+echo '--- PASS: TestB (0.00s)'
+printf 'ok  \texample.com/b\t0.010s\n'
+`
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		// The streams are merged as go test wrote them.
+		{args: nil, want: "from fd 2\n--- PASS: TestA (0.00s)\nok  \texample.com/a\t0.010s\n--- PASS: TestB (0.00s)\n"},
+		// The line from stderr is tagged and follows the output
+		// of its package.
+		{args: []string{"-separate-streams"}, want: "--- PASS: TestA (0.00s)\nok  \texample.com/a\t0.010s\n" + stderrTag + "from fd 2\n--- PASS: TestB (0.00s)\n"},
+	} {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			cmd, cleanup := stubGo(t, script, append([]string{"-color", "never", "-v"}, tt.args...)...)
+			defer cleanup()
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(out), tt.want) {
+				t.Errorf("output doesn't start with:\n%s\ngot:\n%s", tt.want, out)
+			}
+		})
+	}
+}
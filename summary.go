@@ -0,0 +1,176 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ResultSummary accumulates the results of a run as its output
+// is parsed. Lines are parsed with mu held, and the counts may
+// be read with snapshot from other goroutines, such as those
+// handling signals, while the run goes on.
+type ResultSummary struct {
+	// mu guards the fields while a line is parsed. The counts
+	// must not change without it.
+	mu sync.Mutex
+
+	pass    int
+	fail    int
+	skipped int
+
+	// current is the test the output being parsed belongs to,
+	// and pkg its package if known.
+	current string
+	pkg     string
+	// output holds the output of tests that haven't passed or
+	// been skipped, keyed by test name.
+	output map[string][]string
+	// failed lists the failed tests in the order they failed.
+	failed []string
+	// tests holds the result of every test, and pkgStart the
+	// index of the first test of the package being parsed.
+	tests    []testResult
+	pkgStart int
+	// lines counts the lines of output printed by each test,
+	// and pkgLines those printed by the tests of the packages
+	// whose output isn't over.
+	lines    map[testKey]int
+	pkgLines map[testKey]int
+	// truncated counts the lines left out of the output of
+	// each test by -max-lines-per-test.
+	truncated map[string]int
+	// held holds back the output of tests for -failures-last
+	// until they pass, or the run is over if they fail.
+	held      map[string][]heldLine
+	heldOrder []string
+	released  map[string]bool
+	// example is set after a failed example to "failed", and
+	// then to "got" or "want" in the blocks of its output.
+	example string
+	// lastLog is the latest log line, held back by -skip-reasons.
+	lastLog *testLog
+	// sorted holds back the output for -sort-output, and
+	// sortStart is the index of the first line of the package
+	// being parsed.
+	sorted    []sortedLine
+	sortStart int
+	// focused holds back the output of the package being
+	// tested for -focus-failures.
+	focused []focusLine
+	// blank is set after a blank line, for -collapse-blanks.
+	blank bool
+	// stack is the goroutine stack being parsed, if any.
+	stack *stack
+	// trees groups the output of tests for -tree.
+	trees []*testTree
+	// races counts the data races reported by the race detector.
+	races int
+	// goErrors counts the errors of the go command, such as
+	// failing to download a module.
+	goErrors int
+	// noMatch counts the packages with no tests matching -run,
+	// and ranPackages those that passed running some.
+	noMatch     int
+	ranPackages int
+	// noTestFiles counts the packages without test files.
+	noTestFiles int
+	// forbidden counts the lines matching -fail-on-output.
+	forbidden int
+	// slowest is the test that took the longest to run.
+	slowest        string
+	slowestElapsed time.Duration
+	// histogram counts the tests in each duration bucket.
+	histogram []int
+	// statusElapsed sums the time of top-level tests by status.
+	statusElapsed map[string]time.Duration
+	// eventTime is the time of the -json event being parsed.
+	eventTime time.Time
+	// elapsed is how long the run took.
+	elapsed time.Duration
+	// packagesElapsed sums the time packages reported running
+	// their tests for.
+	packagesElapsed time.Duration
+	// comparison is how the run compares to the previous one.
+	comparison *comparison
+	// profile is how gotest spent the run.
+	profile profile
+	// parallel is the number of packages tested in parallel,
+	// if set with -p.
+	parallel int
+	// listed counts the tests listed by go test -list.
+	listed int
+	// packages holds the import paths of the packages tested.
+	packages map[string]bool
+	// cachedPackages lists the packages whose results came
+	// from the test cache, out of the testedPackages with tests.
+	cachedPackages []string
+	testedPackages int
+	// rerunCached is set if the cached packages are tested again,
+	// so that only the rerun counts. checkpoint is the end of
+	// the last package, which their results are forgotten back to.
+	rerunCached bool
+	checkpoint  checkpoint
+	// pkgStatuses lists the status of every package tested:
+	// "ok", "FAIL", "flaky" or "?".
+	pkgStatuses []string
+	// failedPackages lists the packages that failed.
+	failedPackages []string
+	// flaky counts the failures of known flaky tests, and
+	// flakyPackages lists the packages that failed only
+	// because of them.
+	flaky         int
+	flakyPackages []string
+	// pkgFailures and pkgFlaky count the failed tests of the
+	// package whose output is being parsed. go test prints
+	// the output of each package in one piece.
+	pkgFailures int
+	pkgFlaky    int
+	pkgNoMatch  bool
+	// pkgFailed holds the failed tests of the package, whose
+	// failure may turn out to be that of known flaky subtests.
+	pkgFailed map[string]*failedTest
+	// stoppedBy is the package whose failure stopped the run
+	// with -stop-on-package-fail.
+	stoppedBy string
+
+	// separate is set once a package is over, so a separator
+	// is printed before the output of the next one.
+	separate bool
+
+	// writeErr is the last error writing the output.
+	writeErr error
+	// tee is also written the raw output of go test.
+	tee io.Writer
+	// stderr is set while parsing a line go test wrote to stderr,
+	// if the streams are split.
+	stderr bool
+	// heldStderr holds back the lines from stderr for
+	// -separate-streams until the package is over.
+	heldStderr []heldLine
+	// signalChild signals go test, if gotest started it.
+	signalChild func(os.Signal)
+}
+
+// counts are the counts of a run so far.
+type counts struct {
+	pass, skipped, fail int
+}
+
+// snapshot returns the counts so far. It is safe to call
+// while the output is being parsed.
+func (r *ResultSummary) snapshot() counts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return counts{r.pass, r.skipped, r.fail}
+}
+
+// total is the number of tests counted.
+func (c counts) total() int {
+	return c.pass + c.skipped + c.fail
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// resultTemplate renders test result lines if -template is set.
+var resultTemplate *template.Template
+
+// result is the data passed to the -template template.
+type result struct {
+	Status  string // PASS, FAIL or SKIP
+	Test    string
+	Package string // only known in -json mode
+	Elapsed time.Duration
+}
+
+// loadTemplate parses the -template flag.
+func loadTemplate() error {
+	if *templateText == "" {
+		return nil
+	}
+	t, err := template.New("result").Parse(*templateText)
+	if err != nil {
+		return err
+	}
+	resultTemplate = t
+	return nil
+}
+
+// renderResult renders a test result line with the result
+// template, keeping the indentation of subtests. It reports
+// false if line isn't a test result.
+func renderResult(line, trimmed, pkg string) (string, bool) {
+	if resultTemplate == nil {
+		return "", false
+	}
+	status := resultStatus(trimmed)
+	if status == "" {
+		return "", false
+	}
+	elapsed, _ := testElapsed(trimmed)
+	var buf bytes.Buffer
+	buf.WriteString(line[:len(line)-len(strings.TrimLeft(line, " \t"))])
+	err := resultTemplate.Execute(&buf, result{
+		Status:  status,
+		Test:    testName(trimmed),
+		Package: pkg,
+		Elapsed: elapsed,
+	})
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
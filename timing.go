@@ -0,0 +1,192 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// histogramBuckets are the upper bounds of the -histogram buckets.
+// Tests slower than the last bound fall in an extra bucket.
+var histogramBuckets = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// histogramWidth is the length of the longest -histogram bar.
+const histogramWidth = 40
+
+// resultHeaders are the prefixes of the lines reporting
+// the outcome of a test.
+var resultHeaders = []string{"--- PASS:", "--- FAIL:", "--- SKIP:"}
+
+// testElapsed returns the elapsed time reported by a trimmed
+// result line such as "--- PASS: TestA (1.23s)".
+func testElapsed(trimmed string) (time.Duration, bool) {
+	isResult := false
+	for _, h := range resultHeaders {
+		if strings.HasPrefix(trimmed, h) {
+			isResult = true
+			break
+		}
+	}
+	if !isResult || !strings.HasSuffix(trimmed, ")") {
+		return 0, false
+	}
+	i := strings.LastIndex(trimmed, " (")
+	if i < 0 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(trimmed[i+2 : len(trimmed)-1])
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// timeTest records the elapsed time of the test reported
+// by a trimmed result line.
+func (r *ResultSummary) timeTest(trimmed string) {
+	d, ok := testElapsed(trimmed)
+	if !ok {
+		return
+	}
+	if r.histogram == nil {
+		r.histogram = make([]int, len(histogramBuckets)+1)
+	}
+	r.histogram[bucket(d)]++
+	// The time of subtests is part of that of their parent.
+	if !strings.Contains(testName(trimmed), "/") {
+		if r.statusElapsed == nil {
+			r.statusElapsed = make(map[string]time.Duration)
+		}
+		r.statusElapsed[resultStatus(trimmed)] += d
+	}
+	if r.slowest == "" || d > r.slowestElapsed {
+		r.slowest = testName(trimmed)
+		r.slowestElapsed = d
+	}
+}
+
+// packageElapsed returns the elapsed time reported by a trimmed
+// package result line such as "ok  example.com/foo 0.1s". Cached
+// results and packages that failed to build report none.
+func packageElapsed(trimmed string) (time.Duration, bool) {
+	f := strings.Fields(trimmed)
+	if len(f) < 3 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(f[2])
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// overhead estimates the time the run spent outside of tests,
+// mostly building them, as its wall time less the time packages
+// reported running their tests for. Packages tested in parallel
+// make it an underestimate, down to zero.
+func (r *ResultSummary) overhead() time.Duration {
+	d := r.elapsed - r.packagesElapsed
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// isFastPass reports whether a trimmed line is the result of a
+// test that passed faster than -hide-faster-than. With -tree,
+// results head the output of their subtests, so none is.
+func isFastPass(trimmed string) bool {
+	if *hideFasterThan <= 0 || *tree || !strings.HasPrefix(trimmed, "--- PASS:") {
+		return false
+	}
+	d, ok := testElapsed(trimmed)
+	return ok && d < *hideFasterThan
+}
+
+// bucket returns the index of the histogram bucket d falls in.
+func bucket(d time.Duration) int {
+	for i, b := range histogramBuckets {
+		if d < b {
+			return i
+		}
+	}
+	return len(histogramBuckets)
+}
+
+// printHistogram prints the distribution of test durations
+// as a bar chart.
+func (r *ResultSummary) printHistogram() {
+	max := 0
+	for _, n := range r.histogram {
+		if n > max {
+			max = n
+		}
+	}
+	if max == 0 {
+		return
+	}
+	color.Cyan("Durations:")
+	for i, n := range r.histogram {
+		label := ">=" + histogramBuckets[len(histogramBuckets)-1].String()
+		if i < len(histogramBuckets) {
+			label = "<" + histogramBuckets[i].String()
+		}
+		bar := strings.Repeat("#", n*histogramWidth/max)
+		if n > 0 && bar == "" {
+			bar = "#"
+		}
+		color.White("  %-6s %s %d", label, bar, n)
+	}
+}
+
+// printElapsedByStatus prints the time spent in tests
+// that passed, failed and were skipped.
+func (r *ResultSummary) printElapsedByStatus() {
+	if len(r.statusElapsed) == 0 {
+		return
+	}
+	color.Cyan("Time by status:")
+	color.Green("  PASS: %.2fs", r.statusElapsed["PASS"].Seconds())
+	color.Yellow("  SKIP: %.2fs", r.statusElapsed["SKIP"].Seconds())
+	color.Red("  FAIL: %.2fs", r.statusElapsed["FAIL"].Seconds())
+}
+
+// printOvertime prints the tests that took longer than
+// -per-test-timeout.
+func (r *ResultSummary) printOvertime() {
+	var over []testResult
+	for _, t := range r.tests {
+		if t.Elapsed > *perTestTimeout {
+			over = append(over, t)
+		}
+	}
+	if len(over) == 0 {
+		return
+	}
+	color.Red("Tests over the %v per-test timeout:", *perTestTimeout)
+	for _, t := range over {
+		color.Red("  %s (%.2fs)", trimPackage(qualifiedName(t)), t.Elapsed.Seconds())
+	}
+}
+
+var (
+	testDuration    = regexp.MustCompile(`\(\d+(\.\d+)?s\)`)
+	packageDuration = regexp.MustCompile(`^(\s*(ok|FAIL)\s+\S+\s+)\d+(\.\d+)?s`)
+)
+
+// redactDurations replaces the durations of tests and packages
+// in line with zero, for output that is the same every run.
+func redactDurations(line string) string {
+	line = testDuration.ReplaceAllString(line, "(0.00s)")
+	return packageDuration.ReplaceAllString(line, "${1}0.000s")
+}
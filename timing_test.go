@@ -0,0 +1,151 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTestElapsed(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want time.Duration
+		ok   bool
+	}{
+		{"--- PASS: TestA (0.00s)", 0, true},
+		{"--- FAIL: TestB (1.25s)", 1250 * time.Millisecond, true},
+		{"--- SKIP: TestC (8.21s)", 8210 * time.Millisecond, true},
+		{"--- PASS: TestD/with_(parens) (12.5s)", 12500 * time.Millisecond, true},
+		{"--- PASS: TestE", 0, false},
+		{"--- PASS: TestF (soon)", 0, false},
+		{"ok  	example.com/foo	1.000s", 0, false},
+		{"=== RUN   TestG", 0, false},
+	} {
+		got, ok := testElapsed(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("testElapsed(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSlowestTest(t *testing.T) {
+	r := &ResultSummary{}
+	for _, line := range []string{
+		"--- PASS: TestA (0.50s)",
+		"--- PASS: TestBigThing (8.21s)",
+		"--- FAIL: TestB (2.00s)",
+		"--- SKIP: TestC (0.00s)",
+		"--- PASS: TestD (8.21s)",
+		"--- PASS: TestE",
+	} {
+		r.timeTest(line)
+	}
+	// Ties keep the first test to take that long.
+	if r.slowest != "TestBigThing" || r.slowestElapsed != 8210*time.Millisecond {
+		t.Errorf("slowest = %s (%v), want TestBigThing (8.21s)", r.slowest, r.slowestElapsed)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	r := &ResultSummary{}
+	for _, line := range []string{
+		"--- PASS: TestA (0.00s)",
+		"--- PASS: TestB (0.01s)",
+		"--- PASS: TestC (0.09s)",
+		"--- FAIL: TestD (0.10s)",
+		"--- SKIP: TestE (0.99s)",
+		"--- PASS: TestF (1.00s)",
+		"--- PASS: TestG (60.00s)",
+		"--- PASS: TestH",
+	} {
+		r.timeTest(line)
+	}
+	want := []int{1, 2, 2, 2}
+	if len(r.histogram) != len(want) {
+		t.Fatalf("histogram = %v, want %v", r.histogram, want)
+	}
+	for i := range want {
+		if r.histogram[i] != want[i] {
+			t.Errorf("histogram = %v, want %v", r.histogram, want)
+			break
+		}
+	}
+}
+
+func TestElapsedByStatus(t *testing.T) {
+	r := &ResultSummary{}
+	for _, line := range []string{
+		"--- PASS: TestA (0.50s)",
+		"--- PASS: TestB (1.25s)",
+		"    --- PASS: TestB/sub (1.00s)",
+		"--- FAIL: TestC (2.00s)",
+		"    --- FAIL: TestC/sub (2.00s)",
+		"--- SKIP: TestD (0.00s)",
+	} {
+		r.timeTest(line)
+	}
+	// Subtests are part of the time of their parent.
+	for status, want := range map[string]time.Duration{
+		"PASS": 1750 * time.Millisecond,
+		"FAIL": 2 * time.Second,
+		"SKIP": 0,
+	} {
+		if got := r.statusElapsed[status]; got != want {
+			t.Errorf("%s time = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRedactDurations(t *testing.T) {
+	for _, tt := range []struct {
+		line, want string
+	}{
+		{"--- PASS: TestA (0.42s)", "--- PASS: TestA (0.00s)"},
+		{"    --- FAIL: TestA/sub_(1) (12.5s)", "    --- FAIL: TestA/sub_(1) (0.00s)"},
+		{"ok  	example.com/a	1.234s", "ok  	example.com/a	0.000s"},
+		{"FAIL	example.com/a	10.5s", "FAIL	example.com/a	0.000s"},
+		{"ok  	example.com/a	(cached)", "ok  	example.com/a	(cached)"},
+		{"    a_test.go:5: 2.5s", "    a_test.go:5: 2.5s"},
+	} {
+		if got := redactDurations(tt.line); got != tt.want {
+			t.Errorf("redactDurations(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	for _, tt := range []struct {
+		elapsed time.Duration
+		lines   []string
+		want    time.Duration
+	}{
+		{
+			elapsed: 5 * time.Second,
+			lines:   []string{"ok  	example.com/a	1.500s", "FAIL	example.com/b	1.000s", "ok  	example.com/c	(cached)", "?   	example.com/d	[no test files]"},
+			want:    2500 * time.Millisecond,
+		},
+		{
+			elapsed: time.Second,
+			lines:   []string{"FAIL	example.com/b [build failed]"},
+			want:    time.Second,
+		},
+		{
+			// Packages tested in parallel report more time
+			// than the run took.
+			elapsed: 2 * time.Second,
+			lines:   []string{"ok  	example.com/a	1.500s", "ok  	example.com/b	1.500s"},
+			want:    0,
+		},
+	} {
+		r := &ResultSummary{elapsed: tt.elapsed}
+		for _, line := range tt.lines {
+			r.trackPackage(line)
+		}
+		if got := r.overhead(); got != tt.want {
+			t.Errorf("overhead of %v with %q = %v, want %v", tt.elapsed, tt.lines, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// titleInterval is how often the terminal title is updated.
+const titleInterval = 200 * time.Millisecond
+
+// title shows the live counts of a run in the terminal title.
+// A nil *title does nothing.
+type title struct {
+	w    io.Writer
+	last time.Time
+	text string
+}
+
+// newTitle returns a title writing to w, or nil if w isn't
+// a terminal. It saves the current title so it can be
+// restored once the run is over.
+func newTitle(w io.Writer, tty bool) *title {
+	if !tty {
+		return nil
+	}
+	// Push the current title on the xterm title stack.
+	fmt.Fprint(w, "\033[22;0t")
+	return &title{w: w}
+}
+
+// stdoutTitle returns a title for standard output if
+// -title is set and standard output is a terminal.
+func stdoutTitle() *title {
+	if !*liveTitle {
+		return nil
+	}
+	fd := os.Stdout.Fd()
+	return newTitle(os.Stdout, isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd))
+}
+
+// update shows the counts of summary, at most once
+// per titleInterval.
+func (t *title) update(summary *ResultSummary) {
+	if t == nil || time.Since(t.last) < titleInterval {
+		return
+	}
+	c := summary.snapshot()
+	text := fmt.Sprintf("gotest: %d✓ %d✗", c.pass, c.fail)
+	if text == t.text {
+		return
+	}
+	fmt.Fprintf(t.w, "\033]0;%s\007", text)
+	t.last, t.text = time.Now(), text
+}
+
+// restore restores the title saved by newTitle.
+func (t *title) restore() {
+	if t == nil {
+		return
+	}
+	// Pop the title saved by newTitle.
+	fmt.Fprint(t.w, "\033[23;0t")
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTitle(t *testing.T) {
+	for _, tt := range []struct {
+		tty  bool
+		want string
+	}{
+		{tty: true, want: "\033[22;0t\033]0;gotest: 1✓ 2✗\007\033[23;0t"},
+		{tty: false, want: ""},
+	} {
+		var b bytes.Buffer
+		ti := newTitle(&b, tt.tty)
+		ti.update(&ResultSummary{pass: 1, fail: 2})
+		ti.restore()
+		if got := b.String(); got != tt.want {
+			t.Errorf("tty %v: wrote %q, want %q", tt.tty, got, tt.want)
+		}
+	}
+}
+
+func TestTitleNotTerminal(t *testing.T) {
+	// The output of the test binary is a pipe.
+	cmd, cleanup := stubGo(t, "echo '--- PASS: TestA (0.00s)'\n", "-title", "-v")
+	defer cleanup()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "\033]0;") || strings.Contains(string(out), "\033[22;0t") {
+		t.Errorf("title set in output that isn't a terminal: %q", out)
+	}
+}
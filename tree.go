@@ -0,0 +1,147 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// treeIndent indents subtests under their parent with -tree.
+const treeIndent = "    "
+
+// testTree is the output of a top-level test and its subtests,
+// grouped for -tree.
+type testTree struct {
+	root  string
+	done  bool // the root test has finished
+	order []string
+	nodes map[string]*treeNode
+}
+
+type treeNode struct {
+	result *heldLine
+	status string
+	output []heldLine
+}
+
+// node returns the node of test name, adding it and
+// its missing parents to the tree.
+func (t *testTree) node(name string) *treeNode {
+	n, ok := t.nodes[name]
+	if !ok {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			t.node(name[:i])
+		}
+		n = &treeNode{}
+		t.nodes[name] = n
+		t.order = append(t.order, name)
+	}
+	return n
+}
+
+// children returns the direct subtests of name in the order
+// they started.
+func (t *testTree) children(name string) []string {
+	var names []string
+	for _, n := range t.order {
+		if strings.HasPrefix(n, name+"/") && !strings.Contains(n[len(name)+1:], "/") {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// group adds line, to be printed in color c, to the tree of the
+// top-level test it belongs to. Trees are printed once their
+// top-level test has finished and the output has moved on. It
+// reports whether line was grouped.
+func (r *ResultSummary) group(line, trimmed string, c color.Attribute) bool {
+	root := r.current
+	if i := strings.Index(root, "/"); i >= 0 {
+		root = root[:i]
+	}
+	r.printTrees(root)
+	if root == "" {
+		return false
+	}
+
+	var t *testTree
+	for _, tt := range r.trees {
+		if tt.root == root {
+			t = tt
+		}
+	}
+	if t == nil {
+		t = &testTree{root: root, nodes: make(map[string]*treeNode)}
+		r.trees = append(r.trees, t)
+	}
+	n := t.node(r.current)
+	switch status := resultStatus(trimmed); {
+	case status != "":
+		n.result = &heldLine{strings.TrimSpace(line), c}
+		n.status = status
+		if r.current == root {
+			t.done = true
+		}
+	case testName(trimmed) != "":
+		// Drop the markers of parallel tests.
+	default:
+		n.output = append(n.output, heldLine{line, c})
+	}
+	return true
+}
+
+// printTrees prints the trees of the finished top-level tests
+// other than current. If current is "", all trees are printed.
+func (r *ResultSummary) printTrees(current string) {
+	var rest []*testTree
+	for _, t := range r.trees {
+		if current != "" && (t.root == current || !t.done) {
+			rest = append(rest, t)
+			continue
+		}
+		r.printTree(t)
+	}
+	r.trees = rest
+}
+
+// printTree prints t with subtests indented under their parent
+// and the top-level test colored by the outcome of the tree.
+func (r *ResultSummary) printTree(t *testTree) {
+	outcome := "PASS"
+	for _, name := range t.order {
+		switch t.nodes[name].status {
+		case "FAIL":
+			outcome = "FAIL"
+		case "SKIP":
+			if outcome == "PASS" && name == t.root {
+				outcome = "SKIP"
+			}
+		}
+	}
+	r.printNode(t, t.root, map[string]color.Attribute{"PASS": pass, "FAIL": fail, "SKIP": skip}[outcome])
+}
+
+// printNode prints the test name of t and its subtests, with
+// its result in color c.
+func (r *ResultSummary) printNode(t *testTree, name string, c color.Attribute) {
+	n := t.nodes[name]
+	indent := strings.Repeat(treeIndent, strings.Count(name, "/"))
+	if n.result != nil {
+		r.println(indent+n.result.line, c)
+	}
+	for _, l := range n.output {
+		r.println(indent+treeIndent+strings.TrimSpace(l.line), l.c)
+	}
+	for _, sub := range t.children(name) {
+		var c color.Attribute
+		if res := t.nodes[sub].result; res != nil {
+			c = res.c
+		}
+		r.printNode(t, sub, c)
+	}
+}
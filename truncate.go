@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// truncate reports whether a line of output is to be left
+// out because its test printed more than -max-lines-per-test
+// lines already. The number of lines left out is printed
+// before the result of the test, or at the end of its package
+// if the test never finishes.
+func (r *ResultSummary) truncate(trimmed string) bool {
+	if *maxLinesPerTest <= 0 {
+		return false
+	}
+	switch {
+	case isPackageLine(trimmed):
+		r.printTruncated("")
+		return false
+	case resultStatus(trimmed) != "":
+		r.printTruncated(r.current)
+		return false
+	case r.current == "" || testName(trimmed) != "" || r.pkgLines[testKey{r.pkg, r.current}] <= *maxLinesPerTest:
+		return false
+	}
+	if r.truncated == nil {
+		r.truncated = make(map[string]int)
+	}
+	r.truncated[r.current]++
+	return true
+}
+
+// endLines adds the lines printed by the tests of pkg, whose
+// output is over, to those of the run, so that tests of the
+// same name in the next packages start over.
+func (r *ResultSummary) endLines(pkg string) {
+	for k, n := range r.pkgLines {
+		if k.pkg == "" || k.pkg == pkg {
+			if r.lines == nil {
+				r.lines = make(map[testKey]int)
+			}
+			r.lines[testKey{pkg, k.test}] += n
+		}
+	}
+	r.forgetLines(pkg)
+}
+
+// forgetLines forgets the lines printed by the tests of pkg,
+// whose output is over.
+func (r *ResultSummary) forgetLines(pkg string) {
+	for k := range r.pkgLines {
+		if k.pkg == "" || k.pkg == pkg {
+			delete(r.pkgLines, k)
+		}
+	}
+}
+
+// printTruncated prints how many lines of the output of test
+// were left out, or of every test if test is "".
+func (r *ResultSummary) printTruncated(test string) {
+	tests := []string{test}
+	if test == "" {
+		tests = tests[:0]
+		for t := range r.truncated {
+			tests = append(tests, t)
+		}
+		sort.Strings(tests)
+	}
+	for _, t := range tests {
+		n, ok := r.truncated[t]
+		if !ok {
+			continue
+		}
+		delete(r.truncated, t)
+		if *countsOnly {
+			continue
+		}
+		line := fmt.Sprintf("    ... (output of %s truncated, %d more lines)", t, n)
+		r.emit(line, line, neutral)
+	}
+}
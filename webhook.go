@@ -0,0 +1,75 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// webhookTimeout bounds the time spent posting to -webhook.
+const webhookTimeout = 5 * time.Second
+
+// webhookTemplate renders the body posted to -webhook if
+// -webhook-template is set.
+var webhookTemplate *template.Template
+
+// loadWebhookTemplate parses the -webhook-template flag. The
+// json function quotes a value as JSON, for bodies such as
+// {"text": {{json .RunID}}}.
+func loadWebhookTemplate() error {
+	if *webhookTemplateText == "" {
+		return nil
+	}
+	t, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(*webhookTemplateText)
+	if err != nil {
+		return err
+	}
+	webhookTemplate = t
+	return nil
+}
+
+// postWebhook posts the summary as JSON to -webhook, or the
+// body rendered by -webhook-template from the same fields.
+// Failing to post it doesn't fail the run.
+func postWebhook(summary *ResultSummary) {
+	if *webhookURL == "" {
+		return
+	}
+	if err := webhookPost(*webhookURL, newJSONSummary(summary)); err != nil {
+		log.Printf("warning: posting to webhook: %v", err)
+	}
+}
+
+func webhookPost(url string, s jsonSummary) error {
+	var body bytes.Buffer
+	if webhookTemplate != nil {
+		if err := webhookTemplate.Execute(&body, s); err != nil {
+			return err
+		}
+	} else if err := json.NewEncoder(&body).Encode(s); err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var webhookSummary = jsonSummary{
+	RunID:    "r1",
+	Total:    3,
+	Pass:     2,
+	Fail:     1,
+	Elapsed:  1.5,
+	Failures: []jsonFailure{{Package: "example.com/x", Test: "TestB", Elapsed: 0.25}},
+}
+
+// webhookServer returns a stub server responding with code and
+// the content type and body of the last request it got.
+func webhookServer(code int) (*httptest.Server, *string, *string) {
+	var contentType, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		contentType, body = r.Header.Get("Content-Type"), string(b)
+		w.WriteHeader(code)
+	}))
+	return ts, &contentType, &body
+}
+
+func TestWebhookPost(t *testing.T) {
+	ts, contentType, body := webhookServer(http.StatusOK)
+	defer ts.Close()
+
+	if err := webhookPost(ts.URL, webhookSummary); err != nil {
+		t.Fatal(err)
+	}
+	if *contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", *contentType)
+	}
+	want := `{"run_id":"r1","total":3,"pass":2,"fail":1,"skip":0,"elapsed":1.5,"failures":[{"package":"example.com/x","test":"TestB","elapsed":0.25}]}` + "\n"
+	if *body != want {
+		t.Errorf("body = %s, want %s", *body, want)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	ts, _, body := webhookServer(http.StatusOK)
+	defer ts.Close()
+
+	text := *webhookTemplateText
+	defer func() {
+		*webhookTemplateText = text
+		webhookTemplate = nil
+	}()
+	*webhookTemplateText = `{"text": {{json (printf "%d of %d failed in run %s" .Fail .Total .RunID)}}}`
+	if err := loadWebhookTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhookPost(ts.URL, webhookSummary); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text": "1 of 3 failed in run r1"}`; *body != want {
+		t.Errorf("body = %s, want %s", *body, want)
+	}
+}
+
+func TestWebhookPostError(t *testing.T) {
+	ts, _, _ := webhookServer(http.StatusInternalServerError)
+	defer ts.Close()
+
+	if err := webhookPost(ts.URL, webhookSummary); err == nil {
+		t.Error("post succeeded, want the error status reported")
+	}
+}
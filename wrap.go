@@ -0,0 +1,121 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wrapIndent indents the lines a line is wrapped onto past
+// the indentation of the line itself.
+const wrapIndent = "    "
+
+// sgr matches a color escape sequence at the start of a string.
+var sgr = regexp.MustCompile(`^\x1b\[[0-9;]*m`)
+
+// wrapWidth is the width lines are wrapped to with -wrap.
+var wrapWidth int
+
+// wrapper breaks a line into lines no wider than width,
+// keeping track of the colors in effect.
+type wrapper struct {
+	width  int
+	hang   string
+	lines  []string
+	cur    strings.Builder
+	col    int
+	active []string // color escape sequences in effect
+}
+
+// wrap breaks line at spaces into lines no wider than width,
+// indenting the lines after the first past the indentation of
+// line. Words wider than a line are broken anywhere. Escape
+// sequences take up no width, and the colors in effect at a
+// break are ended before it and started again after it, so
+// that each line is colored on its own.
+func wrap(line string, width int) []string {
+	rest := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(rest)]
+	w := &wrapper{width: width, hang: indent + wrapIndent}
+	if advance(0, w.hang) >= width/2 {
+		w.hang = ""
+	}
+	w.write(indent)
+	for i, word := range strings.Split(rest, " ") {
+		if i > 0 {
+			if advance(w.col+1, word) > width && w.col > advance(0, w.hang) {
+				w.newLine()
+			} else {
+				w.write(" ")
+			}
+		}
+		w.writeWord(word)
+	}
+	w.lines = append(w.lines, w.cur.String())
+	return w.lines
+}
+
+// writeWord writes word, breaking it if it doesn't fit a line.
+func (w *wrapper) writeWord(word string) {
+	for word != "" {
+		if m := sgr.FindString(word); m != "" {
+			w.write(m)
+			if m == "\x1b[0m" || m == "\x1b[m" {
+				w.active = nil
+			} else {
+				w.active = append(w.active, m)
+			}
+			word = word[len(m):]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		if next := advance(w.col, string(r)); next > w.width && w.col > advance(0, w.hang) {
+			w.newLine()
+		}
+		w.write(word[:size])
+		word = word[size:]
+	}
+}
+
+// newLine ends the current line and starts the next one with
+// the hanging indentation and the colors in effect.
+func (w *wrapper) newLine() {
+	if len(w.active) > 0 {
+		w.cur.WriteString("\x1b[0m")
+	}
+	w.lines = append(w.lines, w.cur.String())
+	w.cur.Reset()
+	w.col = 0
+	w.write(w.hang)
+	w.cur.WriteString(strings.Join(w.active, ""))
+}
+
+func (w *wrapper) write(s string) {
+	w.cur.WriteString(s)
+	w.col = advance(w.col, s)
+}
+
+// advance returns the column after printing s from column col.
+// Tabs stop every 8 columns.
+func advance(col int, s string) int {
+	for s != "" {
+		if strings.HasPrefix(s, "\x1b[") {
+			if m := sgr.FindString(s); m != "" {
+				s = s[len(m):]
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		if r == '\t' {
+			col += 8 - col%8
+		} else {
+			col++
+		}
+		s = s[size:]
+	}
+	return col
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{"short line", 20, []string{"short line"}},
+		{"one two three four", 10, []string{"one two", "    three", "    four"}},
+		{"  indented words here", 14, []string{"  indented", "      words", "      here"}},
+		// Lines aren't indented when the indentation takes half a line.
+		{"one two three", 6, []string{"one", "two", "three"}},
+		{"abcdefghijklmnop", 10, []string{"abcdefghij", "    klmnop"}},
+		{"FAIL\tpkg", 11, []string{"FAIL\tpkg"}},
+		{"FAIL\tpkg", 10, []string{"FAIL\tpk", "    g"}},
+		// Colors take up no width and are started again on each line.
+		{"\x1b[31mred words here\x1b[0m", 10, []string{"\x1b[31mred words\x1b[0m", "    \x1b[31mhere\x1b[0m"}},
+	} {
+		if got := wrap(tt.line, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrapLines(t *testing.T) {
+	cmd := gotestCmd("-color", "always", "-wrap", "-dry-parse", filepath.Join("testdata", "fail.txt"))
+	cmd.Env = append(cmd.Env, "COLUMNS=30")
+	out, err := cmd.Output()
+	if code := exitCodeOf(t, err); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	// Each of the lines the package line is wrapped onto is colored.
+	want := fmt.Sprintf("\033[%dmFAIL\tgithub.com/rakyll/gote\n\033[0m\033[%dm    st/example\t1.002s\n\033[0m", fail, fail)
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output lacks %q:\n%q", want, out)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if n := advance(0, line); n > 30 {
+			t.Errorf("line %q is %d columns wide, want at most 30", line, n)
+		}
+	}
+}